@@ -1,49 +1,333 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"minesense-backend/config"
 	"minesense-backend/delivery/controllers"
 	"minesense-backend/delivery/router"
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
 	"minesense-backend/infrastructure/database"
+	"minesense-backend/infrastructure/imaging"
+	"minesense-backend/infrastructure/metrics"
+	"minesense-backend/infrastructure/mjpeg"
+	"minesense-backend/infrastructure/ratelimit"
+	"minesense-backend/infrastructure/utils"
 	"minesense-backend/infrastructure/websocket"
 	"minesense-backend/usecases"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
-	// Connect to database
-	database.ConnectDB(cfg)
+	// `server migrate ...` manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		switch cfg.StorageDriver {
+		case "postgres":
+			database.ConnectDB(cfg)
+		case "sqlite":
+			database.ConnectSQLite(cfg)
+		default:
+			log.Fatalf("migrate is not supported with STORAGE_DRIVER=%s", cfg.StorageDriver)
+		}
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	// Connect storage and initialize repositories
+	repos := openStorage(cfg)
 
 	// Initialize WebSocket Hub
-	hub := websocket.NewHub()
+	hub := websocket.NewHub(cfg.WSBroadcastQueue, cfg.WSSendBuffer, cfg.WSReplayBuffer)
+	hub.Backend = openBroadcastBackend(cfg)
+	registerHubMetrics(hub)
+
+	// Initialize Use Cases
+	deviceUseCase := usecases.NewDeviceUseCase(repos.Devices)
+	alertUseCase := usecases.NewAlertUseCase(repos.Alerts)
+	userUseCase := usecases.NewUserUseCase(repos.Users)
+	bootstrapAdmin(userUseCase, cfg)
+
+	// WebSocket clients authenticate with their JWT and get their account's current role;
+	// Supervisors are scoped to their devices
+	hub.SetAllowedOrigins(cfg.WSAllowedOrigins)
+	hub.Account = func(ctx context.Context, userID string) (string, bool, error) {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return "", false, nil
+		}
+		return userUseCase.Account(ctx, id)
+	}
+	hub.Authenticate = func(ctx context.Context, token string) (websocket.Identity, error) {
+		claims, err := utils.ParseToken(token, cfg.JWTSecret)
+		if err != nil {
+			return websocket.Identity{}, err
+		}
+		userID, _ := claims["user_id"].(string)
+		role, active, err := hub.Account(ctx, userID)
+		if err != nil {
+			return websocket.Identity{}, websocket.ErrAuthUnavailable
+		}
+		if !active {
+			return websocket.Identity{}, errors.New("account is disabled or no longer exists")
+		}
+		return websocket.Identity{UserID: userID, Role: role}, nil
+	}
+	hub.DeviceInfo = func(ctx context.Context, deviceID string) (websocket.DeviceInfo, error) {
+		id, err := uuid.Parse(deviceID)
+		if err != nil {
+			return websocket.DeviceInfo{}, nil
+		}
+		device, err := deviceUseCase.GetDeviceByID(ctx, id)
+		if errors.Is(err, interfaces.ErrNotFound) {
+			return websocket.DeviceInfo{}, nil
+		}
+		if err != nil {
+			return websocket.DeviceInfo{}, err
+		}
+		info := websocket.DeviceInfo{Zone: device.Location}
+		if device.SupervisorID != nil {
+			info.SupervisorID = device.SupervisorID.String()
+		}
+		if device.WorkerID != nil {
+			info.WorkerID = device.WorkerID.String()
+		}
+		return info, nil
+	}
+	// Clients offline longer than the replay buffer covers still get the alerts they missed
+	hub.ReplayAlerts = func(ctx context.Context, since, until time.Time) ([]websocket.StoredAlert, error) {
+		alerts, err := alertUseCase.GetAlertsBetween(ctx, since, until)
+		if err != nil {
+			return nil, err
+		}
+		stored := make([]websocket.StoredAlert, 0, len(alerts))
+		for i := range alerts {
+			stored = append(stored, websocket.StoredAlert{
+				ID:        alerts[i].ID.String(),
+				CreatedAt: alerts[i].CreatedAt,
+				Event:     entities.NewAlertEvent(&alerts[i]),
+			})
+		}
+		return stored, nil
+	}
 	go hub.Run()
+	retentionPolicy := usecases.RetentionPolicy{
+		RawRetention:    days(cfg.RetentionRawDays),
+		MinuteRetention: days(cfg.RetentionMinuteDays),
+		HourRetention:   days(cfg.RetentionHourDays),
+		AlertRetention:  days(cfg.RetentionAlertDays),
+		PartitionsAhead: cfg.PartitionsAhead,
+		Interval:        cfg.RetentionInterval,
+		BatchSize:       cfg.RetentionBatchSize,
+	}
+	sensorUseCase := usecases.NewSensorUseCase(repos.Sensors, repos.Alerts, repos.Rollups, retentionPolicy)
+	ingestionUseCase := usecases.NewIngestionUseCase(sensorUseCase, hub, cfg.IngestWorkers, cfg.IngestQueueSize)
+	quotaUseCase := usecases.NewQuotaUseCase(
+		ratelimit.NewLimiter(cfg.DeviceRateLimit, cfg.DeviceRateBurst),
+		ratelimit.NewLimiter(cfg.FrameRateLimit, cfg.FrameRateBurst),
+		repos.Alerts, hub, cfg.RateLimitAlertThreshold, cfg.RateLimitAlertWindow,
+	)
 
-	// Initialize Repositories
-	deviceRepo := database.NewDeviceRepo(database.DB)
-	sensorRepo := database.NewSensorRepo(database.DB)
-	alertRepo := database.NewAlertRepo(database.DB)
-	userRepo := database.NewUserRepo(database.DB)
+	frameBroker := mjpeg.NewBroker()
+	registerVideoMetrics(frameBroker)
+	motionUseCase := usecases.NewMotionUseCase(repos.Alerts, repos.Cameras, hub, imaging.SceneAnalyzer{}, usecases.MotionPolicy{
+		Threshold:        cfg.MotionThreshold,
+		DarkBrightness:   cfg.MotionDarkBrightness,
+		FlatContrast:     cfg.MotionFlatContrast,
+		ObstructedFrames: cfg.MotionObstructedFrames,
+		Cooldown:         cfg.MotionAlertCooldown,
+	})
+	blobs := openBlobStore(cfg)
+	imageUseCase := usecases.NewImageUseCase(repos.Images, repos.Devices, blobs, hub, frameBroker, motionUseCase, imaging.Processor{}, utils.NewURLSigner(cfg.JWTSecret), mjpeg.AVIEncoder{}, usecases.MediaConfig{
+		MaxFrameBytes:   cfg.FrameMaxBytes,
+		URLTTL:          cfg.MediaURLTTL,
+		BaseURL:         cfg.PublicBaseURL,
+		ThumbnailWidth:  cfg.ThumbnailWidth,
+		ExportMaxFrames: cfg.ExportMaxFrames,
+	})
+	cameraUseCase := usecases.NewCameraUseCase(repos.Cameras, repos.Devices, hub, usecases.CameraDefaults{
+		Retention:       time.Duration(cfg.FrameRetentionHours) * time.Hour,
+		MotionThreshold: motionUseCase.Policy.Threshold,
+		TargetFPS:       cfg.CameraTargetFPS,
+	})
+	frameRateUseCase := usecases.NewFrameRateUseCase(cameraUseCase, repos.Devices, repos.Images)
+	registerCameraMetrics(frameRateUseCase)
+	frameRetentionUseCase := usecases.NewFrameRetentionUseCase(repos.Images, repos.Devices, blobs, cameraUseCase, cfg.RetentionInterval, cfg.RetentionBatchSize)
 
-	// Initialize Use Cases
-	deviceUseCase := usecases.NewDeviceUseCase(deviceRepo)
-	sensorUseCase := usecases.NewSensorUseCase(sensorRepo, alertRepo)
-	alertUseCase := usecases.NewAlertUseCase(alertRepo)
-	userUseCase := usecases.NewUserUseCase(userRepo)
+	evidenceUseCase := usecases.NewEvidenceUseCase(repos.Alerts, repos.Devices, repos.Images, imageUseCase, usecases.EvidencePolicy{
+		Before:     cfg.EvidenceBefore,
+		After:      cfg.EvidenceAfter,
+		AlertTypes: cfg.EvidenceAlertTypes,
+	})
+
+	retentionUseCase := usecases.NewRetentionUseCase(repos.Rollups, repos.Partitions, repos.Alerts, retentionPolicy)
+
+	// Start background ingestion workers, the retention jobs and the evidence job
+	ingestionUseCase.Start()
+	registerIngestionMetrics(ingestionUseCase)
+	retentionUseCase.Start()
+	evidenceUseCase.Start()
+	frameRetentionUseCase.Start()
 
 	// Initialize Controllers
 	deviceController := controllers.NewDeviceController(deviceUseCase, hub)
-	sensorController := controllers.NewSensorController(sensorUseCase, deviceUseCase, hub)
-	alertController := controllers.NewAlertController(alertUseCase)
+	sensorController := controllers.NewSensorController(sensorUseCase, deviceUseCase, ingestionUseCase, quotaUseCase, hub)
+	alertController := controllers.NewAlertController(alertUseCase, evidenceUseCase)
 	userController := controllers.NewUserController(userUseCase, cfg.JWTSecret)
-	videoController := controllers.NewVideoController(hub)
+	videoController := controllers.NewVideoController(imageUseCase, quotaUseCase, cameraUseCase, frameRateUseCase, hub, frameBroker)
 
 	// Setup Router
-	r := router.SetupRouter(sensorController, deviceController, alertController, userController, videoController, cfg.JWTSecret)
+	ingestLimiter := ratelimit.NewLimiter(cfg.CredentialRateLimit, cfg.CredentialRateBurst)
+	metricsToken := cfg.MetricsToken
+	if cfg.MetricsAddr != "" {
+		metricsToken = ""
+	}
+	r := router.SetupRouter(sensorController, deviceController, alertController, userController, videoController, ingestLimiter, cfg.JWTSecret, metricsToken)
+	metricsSrv := serveMetrics(cfg)
 
 	// Run Server
-	// Note: Render requires the server to bind to 0.0.0.0 (which Gin does by default with Run())
+	// Note: Render requires the server to bind to 0.0.0.0
 	// and listen on the port defined by the PORT environment variable.
-	r.Run("0.0.0.0:" + cfg.Port)
+	srv := &http.Server{
+		Addr:    "0.0.0.0:" + cfg.Port,
+		Handler: r,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed: ", err)
+		}
+	}()
+
+	// Wait for shutdown signal, then stop accepting requests and drain queued readings
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(ctx)
+	}
+	ingestionUseCase.Stop()
+	retentionUseCase.Stop()
+	evidenceUseCase.Stop()
+	frameRetentionUseCase.Stop()
+}
+
+// serveMetrics serves /metrics on its own listen address when METRICS_ADDR is set, so it can be
+// kept off the public network.
+func serveMetrics(cfg *config.Config) *http.Server {
+	if cfg.MetricsAddr == "" {
+		if cfg.MetricsToken == "" {
+			log.Println("Metrics are disabled; set METRICS_ADDR or METRICS_TOKEN to serve /metrics")
+		}
+		return nil
+	}
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	engine.GET("/metrics", metrics.Handler())
+	srv := &http.Server{Addr: cfg.MetricsAddr, Handler: engine}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Metrics server failed: ", err)
+		}
+	}()
+	log.Printf("Serving /metrics on %s", cfg.MetricsAddr)
+	return srv
+}
+
+// bootstrapAdmin creates the initial Admin from the environment on a fresh install, so
+// deployments do not depend on someone reaching POST /register first.
+func bootstrapAdmin(uc *usecases.UserUseCase, cfg *config.Config) {
+	if cfg.BootstrapAdminUsername == "" || cfg.BootstrapAdminPassword == "" {
+		return
+	}
+	created, err := uc.Bootstrap(context.Background(), cfg.BootstrapAdminUsername, cfg.BootstrapAdminPassword)
+	if err != nil {
+		log.Fatalf("Failed to create the initial Admin: %v", err)
+	}
+	if created {
+		log.Printf("Created initial Admin %q", cfg.BootstrapAdminUsername)
+	}
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+func registerIngestionMetrics(uc *usecases.IngestionUseCase) {
+	metrics.NewGaugeFunc("minesense_ingest_queue_depth", "Sensor readings waiting in the ingestion queue.", func() float64 {
+		return float64(uc.QueueDepth())
+	})
+	metrics.NewGaugeFunc("minesense_ingest_queue_capacity", "Total capacity of the ingestion queue.", func() float64 {
+		return float64(uc.QueueCapacity())
+	})
+	metrics.NewCounterFunc("minesense_ingest_processed_total", "Sensor readings persisted and evaluated.", func() float64 {
+		return float64(uc.Processed())
+	})
+	metrics.NewCounterFunc("minesense_ingest_failed_total", "Sensor readings that failed to persist.", func() float64 {
+		return float64(uc.Failed())
+	})
+	metrics.NewCounterFunc("minesense_ingest_rejected_total", "Sensor readings rejected because the queue was full.", func() float64 {
+		return float64(uc.Rejected())
+	})
+}
+
+func registerHubMetrics(hub *websocket.Hub) {
+	metrics.NewGaugeFunc("minesense_ws_clients", "Connected WebSocket clients.", func() float64 {
+		return float64(hub.Clients())
+	})
+	metrics.NewGaugeFunc("minesense_ws_broadcast_queue_depth", "Messages waiting to be fanned out to WebSocket clients.", func() float64 {
+		return float64(hub.QueueDepth())
+	})
+	metrics.NewCounterFunc("minesense_ws_broadcast_dropped_total", "Messages dropped because the broadcast queue was full.", func() float64 {
+		return float64(hub.BroadcastDropped())
+	})
+	metrics.NewCounterFunc("minesense_ws_messages_dropped_total", "Messages not delivered to a client because its send buffer was full.", func() float64 {
+		return float64(hub.MessagesDropped())
+	})
+	metrics.NewCounterFunc("minesense_ws_client_evictions_total", "WebSocket clients disconnected for falling behind.", func() float64 {
+		return float64(hub.Evictions())
+	})
+	metrics.NewCounterFunc("minesense_ws_relay_failed_total", "Messages that could not be relayed to other instances.", func() float64 {
+		return float64(hub.RelayFailed())
+	})
+	metrics.NewCounterFunc("minesense_ws_relay_received_total", "Messages received from other instances.", func() float64 {
+		return float64(hub.RelayReceived())
+	})
+}
+
+func registerVideoMetrics(broker *mjpeg.Broker) {
+	metrics.NewGaugeFunc("minesense_mjpeg_viewers", "Connected MJPEG live video viewers.", func() float64 {
+		return float64(broker.Viewers())
+	})
+	metrics.NewCounterFunc("minesense_mjpeg_frames_skipped_total", "Frames not shown to a live video viewer because it fell behind.", func() float64 {
+		return float64(broker.Skipped())
+	})
+}
+
+func registerCameraMetrics(uc *usecases.FrameRateUseCase) {
+	metrics.NewCounterFunc("minesense_camera_frames_dropped_total", "Camera frames dropped for arriving above the camera's target frame rate.", func() float64 {
+		return float64(uc.Dropped())
+	})
+	metrics.NewCounterFunc("minesense_camera_bytes_received_total", "Camera upload bytes received, including uploads refused by the quota or frame rate.", func() float64 {
+		return float64(uc.BytesReceived())
+	})
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"log"
+
+	"minesense-backend/config"
+	"minesense-backend/domain/interfaces"
+	"minesense-backend/infrastructure/blobstore"
+	"minesense-backend/infrastructure/database"
+	"minesense-backend/infrastructure/memory"
+	"minesense-backend/infrastructure/websocket"
+)
+
+// repositories bundles the storage implementations selected by STORAGE_DRIVER.
+type repositories struct {
+	Devices    interfaces.DeviceRepository
+	Sensors    interfaces.SensorRepository
+	Alerts     interfaces.AlertRepository
+	Images     interfaces.ImageRepository
+	Cameras    interfaces.CameraSettingsRepository
+	Users      interfaces.UserRepository
+	Rollups    interfaces.SensorRollupRepository
+	Partitions interfaces.PartitionRepository
+}
+
+// openStorage connects the configured storage driver and returns its repositories.
+func openStorage(cfg *config.Config) repositories {
+	switch cfg.StorageDriver {
+	case "memory":
+		log.Println("Using in-memory storage; data will be lost on restart")
+		store := memory.NewStore()
+		return repositories{
+			Devices:    memory.NewDeviceRepo(store),
+			Sensors:    memory.NewSensorRepo(store),
+			Alerts:     memory.NewAlertRepo(store),
+			Images:     memory.NewImageRepo(store),
+			Cameras:    memory.NewCameraSettingsRepo(store),
+			Users:      memory.NewUserRepo(store),
+			Rollups:    memory.NewRollupRepo(store),
+			Partitions: memory.NewPartitionRepo(),
+		}
+
+	case "postgres":
+		database.ConnectDB(cfg)
+
+		// Refuse to serve against an outdated schema
+		if err := database.CheckSchema(database.DB); err != nil {
+			log.Fatal(err)
+		}
+		return repositories{
+			Devices:    database.NewDeviceRepo(database.DB),
+			Sensors:    database.NewSensorRepo(database.DB),
+			Alerts:     database.NewAlertRepo(database.DB),
+			Images:     database.NewImageRepo(database.DB),
+			Cameras:    database.NewCameraSettingsRepo(database.DB),
+			Users:      database.NewUserRepo(database.DB),
+			Rollups:    database.NewRollupRepo(database.DB),
+			Partitions: database.NewPartitionRepo(database.DB),
+		}
+
+	case "sqlite":
+		database.ConnectSQLite(cfg)
+
+		// Only this process uses the file, so pending migrations are applied on startup
+		applied, err := database.MigrateUp(database.DB)
+		for _, m := range applied {
+			log.Printf("Applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return repositories{
+			Devices:    database.NewDeviceRepo(database.DB),
+			Sensors:    database.NewSQLiteSensorRepo(database.DB),
+			Alerts:     database.NewAlertRepo(database.DB),
+			Images:     database.NewImageRepo(database.DB),
+			Cameras:    database.NewCameraSettingsRepo(database.DB),
+			Users:      database.NewUserRepo(database.DB),
+			Rollups:    database.NewSQLiteRollupRepo(database.DB),
+			Partitions: database.NewSQLitePartitionRepo(),
+		}
+
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER %q (expected postgres, sqlite or memory)", cfg.StorageDriver)
+		return repositories{}
+	}
+}
+
+// openBroadcastBackend returns the backend selected by WS_BACKEND that relays real-time
+// messages between instances, or nil when this instance serves its clients alone.
+func openBroadcastBackend(cfg *config.Config) websocket.Backend {
+	switch cfg.WSBackend {
+	case "local":
+		return nil
+
+	case "postgres":
+		if cfg.StorageDriver != "postgres" {
+			log.Fatal("WS_BACKEND=postgres requires STORAGE_DRIVER=postgres")
+		}
+		listenDSN := cfg.WSNotifyDatabaseURL
+		if listenDSN == "" {
+			listenDSN = database.PostgresDSN(cfg)
+		}
+		log.Printf("Relaying real-time messages between instances on Postgres channel %q", cfg.WSNotifyChannel)
+		return database.NewNotifyBackend(database.DB, listenDSN, cfg.WSNotifyChannel)
+
+	default:
+		log.Fatalf("Unknown WS_BACKEND %q (expected local or postgres)", cfg.WSBackend)
+		return nil
+	}
+}
+
+// openBlobStore returns the store selected by BLOB_STORE that holds camera frames.
+func openBlobStore(cfg *config.Config) interfaces.BlobStore {
+	switch cfg.BlobStore {
+	case "local":
+		store, err := blobstore.NewLocalStore(cfg.BlobStorePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Storing camera frames under %s", cfg.BlobStorePath)
+		return store
+
+	default:
+		log.Fatalf("Unknown BLOB_STORE %q (expected local)", cfg.BlobStore)
+		return nil
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +19,86 @@ type Config struct {
 	DatabaseURL string // For Render/Supabase
 	JWTSecret   string
 	Port        string
+
+	// /metrics is served on MetricsAddr (e.g. 127.0.0.1:9090) without authentication when set,
+	// otherwise on Port to requests bearing MetricsToken; with neither it is not served
+	MetricsAddr  string
+	MetricsToken string
+
+	// Browser origins allowed to open WebSockets ("*" for any, empty for same-origin only)
+	WSAllowedOrigins []string
+	// Messages queued for the hub, and per-client outgoing buffer before a slow client is evicted
+	WSBroadcastQueue int
+	WSSendBuffer     int
+	// Recent messages kept so reconnecting clients can resume with last_seq
+	WSReplayBuffer int
+	// Relay between instances: local (single instance) or postgres (LISTEN/NOTIFY).
+	// The listener needs a session connection; set WS_NOTIFY_DATABASE_URL when DATABASE_URL is a transaction pooler
+	WSBackend           string
+	WSNotifyChannel     string
+	WSNotifyDatabaseURL string
+
+	// Storage backend: postgres, sqlite or memory
+	StorageDriver string
+	SQLitePath    string
+
+	// Camera frame storage: local (filesystem under BlobStorePath)
+	BlobStore     string
+	BlobStorePath string
+	// Largest accepted JPEG frame, in bytes
+	FrameMaxBytes int64
+	// Lifetime of signed frame URLs, and the public origin that makes them absolute (empty for relative URLs)
+	MediaURLTTL   time.Duration
+	PublicBaseURL string
+	// Frames linked to alerts of EvidenceAlertTypes ("*" for all): from EvidenceBefore the alert to EvidenceAfter it
+	EvidenceBefore     time.Duration
+	EvidenceAfter      time.Duration
+	EvidenceAlertTypes []string
+	// Default frame retention for cameras without their own (0 keeps frames forever)
+	FrameRetentionHours int
+	// Width of generated thumbnails, and the most frames one clip export may hold
+	ThumbnailWidth  int
+	ExportMaxFrames int
+	// Motion detection: the default motion score (0-1) that alerts on restricted-area cameras, at most one
+	// such alert per MotionAlertCooldown; frames darker or flatter than the Motion*Brightness/Contrast
+	// bounds for MotionObstructedFrames frames in a row raise "Camera Obstructed"
+	MotionThreshold        float64
+	MotionAlertCooldown    time.Duration
+	MotionDarkBrightness   float64
+	MotionFlatContrast     float64
+	MotionObstructedFrames int
+	// Frame rate cameras are asked to send at unless configured otherwise (0 for no limit)
+	CameraTargetFPS float64
+
+	// Initial Admin created on startup when there are no users yet (both empty to skip)
+	BootstrapAdminUsername string
+	BootstrapAdminPassword string
+
+	// Upper bound for a single repository call (0 disables)
+	DBQueryTimeout time.Duration
+
+	// Ingestion pipeline
+	IngestWorkers   int
+	IngestQueueSize int
+
+	// Ingestion rate limits (requests per second, 0 disables)
+	DeviceRateLimit         float64
+	DeviceRateBurst         int
+	FrameRateLimit          float64
+	FrameRateBurst          int
+	CredentialRateLimit     float64
+	CredentialRateBurst     int
+	RateLimitAlertThreshold int
+	RateLimitAlertWindow    time.Duration
+
+	// Sensor data retention (0 keeps data forever)
+	RetentionRawDays    int
+	RetentionMinuteDays int
+	RetentionHourDays   int
+	RetentionAlertDays  int
+	PartitionsAhead     int
+	RetentionInterval   time.Duration
+	RetentionBatchSize  int
 }
 
 func LoadConfig() *Config {
@@ -37,6 +120,67 @@ func LoadConfig() *Config {
 		DatabaseURL: getEnv("DATABASE_URL", ""),
 		JWTSecret:   getEnv("JWT_SECRET", "default_secret_change_me"),
 		Port:        getEnv("PORT", "8080"),
+
+		MetricsAddr:  getEnv("METRICS_ADDR", ""),
+		MetricsToken: getEnv("METRICS_TOKEN", ""),
+
+		WSAllowedOrigins: getEnvList("WS_ALLOWED_ORIGINS", nil),
+		WSBroadcastQueue: getEnvInt("WS_BROADCAST_QUEUE", 1024),
+		WSSendBuffer:     getEnvInt("WS_SEND_BUFFER", 256),
+		WSReplayBuffer:   getEnvInt("WS_REPLAY_BUFFER", 1000),
+
+		WSBackend:           getEnv("WS_BACKEND", "local"),
+		WSNotifyChannel:     getEnv("WS_NOTIFY_CHANNEL", "minesense_ws"),
+		WSNotifyDatabaseURL: getEnv("WS_NOTIFY_DATABASE_URL", ""),
+
+		StorageDriver:  getEnv("STORAGE_DRIVER", "postgres"),
+		SQLitePath:     getEnv("SQLITE_PATH", "minesense.db"),
+		DBQueryTimeout: getEnvDuration("DB_QUERY_TIMEOUT", 10*time.Second),
+
+		BlobStore:     getEnv("BLOB_STORE", "local"),
+		BlobStorePath: getEnv("BLOB_STORE_PATH", "data/blobs"),
+		FrameMaxBytes: int64(getEnvInt("FRAME_MAX_BYTES", 2<<20)),
+		MediaURLTTL:   getEnvDuration("MEDIA_URL_TTL", 24*time.Hour),
+		PublicBaseURL: strings.TrimSuffix(getEnv("PUBLIC_BASE_URL", ""), "/"),
+
+		EvidenceBefore:     getEnvDuration("EVIDENCE_WINDOW_BEFORE", 30*time.Second),
+		EvidenceAfter:      getEnvDuration("EVIDENCE_WINDOW_AFTER", 30*time.Second),
+		EvidenceAlertTypes: getEnvList("EVIDENCE_ALERT_TYPES", []string{"Man-Down", "Gas Hazard", "Restricted Area Motion"}),
+
+		FrameRetentionHours: getEnvInt("FRAME_RETENTION_HOURS", 168),
+		ThumbnailWidth:      getEnvInt("THUMBNAIL_WIDTH", 160),
+		ExportMaxFrames:     getEnvInt("EXPORT_MAX_FRAMES", 3000),
+
+		MotionThreshold:        getEnvFloat("MOTION_THRESHOLD", 0.1),
+		MotionAlertCooldown:    getEnvDuration("MOTION_ALERT_COOLDOWN", time.Minute),
+		MotionDarkBrightness:   getEnvFloat("MOTION_DARK_BRIGHTNESS", 0.06),
+		MotionFlatContrast:     getEnvFloat("MOTION_FLAT_CONTRAST", 0.015),
+		MotionObstructedFrames: getEnvInt("MOTION_OBSTRUCTED_FRAMES", 3),
+
+		CameraTargetFPS: getEnvFloat("CAMERA_TARGET_FPS", 5),
+
+		BootstrapAdminUsername: getEnv("BOOTSTRAP_ADMIN_USERNAME", ""),
+		BootstrapAdminPassword: getEnv("BOOTSTRAP_ADMIN_PASSWORD", ""),
+
+		IngestWorkers:   getEnvInt("INGEST_WORKERS", 4),
+		IngestQueueSize: getEnvInt("INGEST_QUEUE_SIZE", 1024),
+
+		DeviceRateLimit:         getEnvFloat("RATE_LIMIT_DEVICE_RPS", 10),
+		DeviceRateBurst:         getEnvInt("RATE_LIMIT_DEVICE_BURST", 10),
+		FrameRateLimit:          getEnvFloat("RATE_LIMIT_FRAME_RPS", 10),
+		FrameRateBurst:          getEnvInt("RATE_LIMIT_FRAME_BURST", 20),
+		CredentialRateLimit:     getEnvFloat("RATE_LIMIT_CREDENTIAL_RPS", 50),
+		CredentialRateBurst:     getEnvInt("RATE_LIMIT_CREDENTIAL_BURST", 100),
+		RateLimitAlertThreshold: getEnvInt("RATE_LIMIT_ALERT_THRESHOLD", 60),
+		RateLimitAlertWindow:    getEnvDuration("RATE_LIMIT_ALERT_WINDOW", time.Minute),
+
+		RetentionRawDays:    getEnvInt("RETENTION_RAW_DAYS", 30),
+		RetentionMinuteDays: getEnvInt("RETENTION_MINUTE_DAYS", 180),
+		RetentionHourDays:   getEnvInt("RETENTION_HOUR_DAYS", 0),
+		RetentionAlertDays:  getEnvInt("RETENTION_ALERT_DAYS", 0),
+		PartitionsAhead:     getEnvInt("PARTITIONS_AHEAD_MONTHS", 3),
+		RetentionInterval:   getEnvDuration("RETENTION_INTERVAL", 5*time.Minute),
+		RetentionBatchSize:  getEnvInt("RETENTION_BATCH_SIZE", 5000),
 	}
 }
 
@@ -46,3 +190,48 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvList reads a comma-separated list; an empty value yields an empty list.
+func getEnvList(key string, fallback []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+		log.Printf("Warning: invalid integer for %s=%q, using default %d", key, value, fallback)
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+		log.Printf("Warning: invalid number for %s=%q, using default %g", key, value, fallback)
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+		log.Printf("Warning: invalid duration for %s=%q, using default %s", key, value, fallback)
+	}
+	return fallback
+}
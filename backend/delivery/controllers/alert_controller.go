@@ -9,11 +9,12 @@ import (
 )
 
 type AlertController struct {
-	AlertUseCase *usecases.AlertUseCase
+	AlertUseCase    *usecases.AlertUseCase
+	EvidenceUseCase *usecases.EvidenceUseCase
 }
 
-func NewAlertController(uc *usecases.AlertUseCase) *AlertController {
-	return &AlertController{AlertUseCase: uc}
+func NewAlertController(uc *usecases.AlertUseCase, euc *usecases.EvidenceUseCase) *AlertController {
+	return &AlertController{AlertUseCase: uc, EvidenceUseCase: euc}
 }
 
 func (c *AlertController) GetAllAlerts(ctx *gin.Context) {
@@ -24,20 +25,35 @@ func (c *AlertController) GetAllAlerts(ctx *gin.Context) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
 			return
 		}
-		alerts, err := c.AlertUseCase.GetAlertsByDevice(deviceID)
+		alerts, err := c.AlertUseCase.GetAlertsByDevice(ctx.Request.Context(), deviceID)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts for device"})
+			respondError(ctx, err, http.StatusInternalServerError, "Failed to fetch alerts for device")
 			return
 		}
 		ctx.JSON(http.StatusOK, alerts)
 		return
 	}
 
-	alerts, err := c.AlertUseCase.GetAllAlerts()
+	alerts, err := c.AlertUseCase.GetAllAlerts(ctx.Request.Context())
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+		respondError(ctx, err, http.StatusInternalServerError, "Failed to fetch alerts")
 		return
 	}
 
 	ctx.JSON(http.StatusOK, alerts)
 }
+
+// GetEvidence returns an alert with the camera frames captured around it.
+func (c *AlertController) GetEvidence(ctx *gin.Context) {
+	alertID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+	evidence, err := c.EvidenceUseCase.GetEvidence(ctx.Request.Context(), alertID)
+	if err != nil {
+		respondError(ctx, err, http.StatusNotFound, "Alert not found")
+		return
+	}
+	ctx.JSON(http.StatusOK, evidence)
+}
@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"context"
+	"minesense-backend/domain/entities"
 	"minesense-backend/infrastructure/websocket"
 	"minesense-backend/usecases"
 	"net/http"
@@ -23,6 +25,8 @@ type CreateDeviceInput struct {
 	DeviceName   string `json:"device_name" binding:"required"`
 	Location     string `json:"location"`
 	SupervisorID string `json:"supervisor_id"` // Optional UUID string
+	WorkerID     string `json:"worker_id"`     // Optional UUID string
+	HasCamera    bool   `json:"has_camera"`    // Whether the device uploads camera frames
 }
 
 func (c *DeviceController) CreateDevice(ctx *gin.Context) {
@@ -42,9 +46,19 @@ func (c *DeviceController) CreateDevice(ctx *gin.Context) {
 		supervisorID = &id
 	}
 
-	device, err := c.DeviceUseCase.RegisterDevice(input.DeviceName, input.Location, supervisorID)
+	var workerID *uuid.UUID
+	if input.WorkerID != "" {
+		id, err := uuid.Parse(input.WorkerID)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid worker ID"})
+			return
+		}
+		workerID = &id
+	}
+
+	device, err := c.DeviceUseCase.RegisterDevice(ctx.Request.Context(), input.DeviceName, input.Location, supervisorID, workerID, input.HasCamera)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create device"})
+		respondError(ctx, err, http.StatusInternalServerError, "Failed to create device")
 		return
 	}
 
@@ -52,9 +66,9 @@ func (c *DeviceController) CreateDevice(ctx *gin.Context) {
 }
 
 func (c *DeviceController) GetAllDevices(ctx *gin.Context) {
-	devices, err := c.DeviceUseCase.GetAllDevices()
+	devices, err := c.DeviceUseCase.GetAllDevices(ctx.Request.Context())
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch devices"})
+		respondError(ctx, err, http.StatusInternalServerError, "Failed to fetch devices")
 		return
 	}
 
@@ -69,9 +83,9 @@ func (c *DeviceController) GetDeviceByID(ctx *gin.Context) {
 		return
 	}
 
-	device, err := c.DeviceUseCase.GetDeviceByID(id)
+	device, err := c.DeviceUseCase.GetDeviceByID(ctx.Request.Context(), id)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		respondError(ctx, err, http.StatusNotFound, "Device not found")
 		return
 	}
 
@@ -87,46 +101,36 @@ func (c *DeviceController) TriggerBuzzer(ctx *gin.Context) {
 	}
 
 	// 1. Get current device state
-	device, err := c.DeviceUseCase.GetDeviceByID(id)
+	device, err := c.DeviceUseCase.GetDeviceByID(ctx.Request.Context(), id)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		respondError(ctx, err, http.StatusNotFound, "Device not found")
 		return
 	}
 
 	// 2. Activate Buzzer for 30 Seconds
 	device.BuzzerActive = true
-	if err := c.DeviceUseCase.UpdateDevice(device); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update buzzer state"})
+	if err := c.DeviceUseCase.UpdateDevice(ctx.Request.Context(), device); err != nil {
+		respondError(ctx, err, http.StatusInternalServerError, "Failed to update buzzer state")
 		return
 	}
 
 	// 3. Broadcast ON command
-	c.Hub.BroadcastData(gin.H{
-		"type":        "device_command",
-		"device_id":   idStr,
-		"command":     "buzzer_on",
-		"is_active":   true,
-		"timestamp":   time.Now(),
-	})
+	c.Hub.BroadcastData(entities.NewDeviceCommandEvent(id, "buzzer_on", true))
 
 	// 4. Start Timer to Turn Off after 30s
 	go func(dID uuid.UUID) {
 		time.Sleep(30 * time.Second)
 		
 		// Retrieve fresh instance to avoid race conditions
-		d, err := c.DeviceUseCase.GetDeviceByID(dID)
+		// (the request context is long gone by now, so use a detached one)
+		bg := context.Background()
+		d, err := c.DeviceUseCase.GetDeviceByID(bg, dID)
 		if err == nil {
 			d.BuzzerActive = false
-			c.DeviceUseCase.UpdateDevice(d)
+			c.DeviceUseCase.UpdateDevice(bg, d)
 			
 			// Broadcast OFF command
-			c.Hub.BroadcastData(gin.H{
-				"type":        "device_command",
-				"device_id":   dID.String(),
-				"command":     "buzzer_off",
-				"is_active":   false,
-				"timestamp":   time.Now(),
-			})
+			c.Hub.BroadcastData(entities.NewDeviceCommandEvent(dID, "buzzer_off", false))
 		}
 	}(id)
 
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"minesense-backend/domain/interfaces"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest is the de-facto status for requests abandoned by the client.
+const statusClientClosedRequest = 499
+
+// respondError writes a JSON error, mapping database timeouts and client cancellations
+// to their own statuses instead of the handler's fallback.
+func respondError(ctx *gin.Context, err error, status int, message string) {
+	switch {
+	case errors.Is(err, interfaces.ErrQueryTimeout):
+		ctx.JSON(http.StatusGatewayTimeout, gin.H{"error": "Database query timed out"})
+	case errors.Is(err, interfaces.ErrQueryCanceled):
+		ctx.AbortWithStatus(statusClientClosedRequest)
+	default:
+		ctx.JSON(status, gin.H{"error": message})
+	}
+}
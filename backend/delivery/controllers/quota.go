@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"minesense-backend/infrastructure/metrics"
+	"minesense-backend/infrastructure/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var droppedIngestRequests = metrics.NewCounterVec(
+	"minesense_ingest_dropped_requests_total",
+	"Ingestion requests rejected by the per-device rate limit.",
+	"device_id",
+)
+
+// rejectOverQuota replies 429 with a Retry-After hint for a device that exceeded its budget.
+func rejectOverQuota(ctx *gin.Context, deviceID uuid.UUID, wait time.Duration) {
+	droppedIngestRequests.Inc(deviceID.String())
+	ctx.Header("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(wait)))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "Device rate limit exceeded"})
+}
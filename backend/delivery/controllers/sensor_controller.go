@@ -2,11 +2,14 @@ package controllers
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
 	"io"
+	"minesense-backend/domain/entities"
+	"minesense-backend/infrastructure/telemetry"
 	"minesense-backend/infrastructure/websocket"
 	"minesense-backend/usecases"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,19 +17,15 @@ import (
 )
 
 type SensorController struct {
-	SensorUseCase *usecases.SensorUseCase
-	DeviceUseCase *usecases.DeviceUseCase
-	Hub           *websocket.Hub
+	SensorUseCase    *usecases.SensorUseCase
+	DeviceUseCase    *usecases.DeviceUseCase
+	IngestionUseCase *usecases.IngestionUseCase
+	QuotaUseCase     *usecases.QuotaUseCase
+	Hub              *websocket.Hub
 }
 
-func NewSensorController(uc *usecases.SensorUseCase, duc *usecases.DeviceUseCase, hub *websocket.Hub) *SensorController {
-	return &SensorController{SensorUseCase: uc, DeviceUseCase: duc, Hub: hub}
-}
-
-type SensorDataInput struct {
-	DeviceID   string          `json:"device_id"`
-	SensorType string          `json:"sensor_type"`
-	Payload    json.RawMessage `json:"payload"`
+func NewSensorController(uc *usecases.SensorUseCase, duc *usecases.DeviceUseCase, iuc *usecases.IngestionUseCase, quc *usecases.QuotaUseCase, hub *websocket.Hub) *SensorController {
+	return &SensorController{SensorUseCase: uc, DeviceUseCase: duc, IngestionUseCase: iuc, QuotaUseCase: quc, Hub: hub}
 }
 
 func (c *SensorController) ReceiveSensorData(ctx *gin.Context) {
@@ -39,87 +38,90 @@ func (c *SensorController) ReceiveSensorData(ctx *gin.Context) {
 	// Restore body just in case
 	ctx.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
-	// 1. Try to parse as standard SensorDataInput
-	var input SensorDataInput
-	var deviceID uuid.UUID
-	var sensorType string
-	var payload json.RawMessage
-
-	// We unmarshal manually to check fields
-	if err := json.Unmarshal(bodyBytes, &input); err == nil && input.DeviceID != "" && input.SensorType != "" {
-		// Standard format
-		id, err := uuid.Parse(input.DeviceID)
-		if err != nil {
+	// Decode JSON, CBOR or Protobuf into the same reading model
+	format := telemetry.FormatFromContentType(ctx.GetHeader("Content-Type"))
+	reading, err := telemetry.Decode(format, bodyBytes)
+	if err != nil {
+		if errors.Is(err, telemetry.ErrInvalidDeviceID) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID format"})
 			return
 		}
-		deviceID = id
-		sensorType = input.SensorType
-		payload = input.Payload
-	} else {
-		// 2. Fallback: Flat JSON (Telemetry)
-		// Try to get DeviceID from Context (Auth Token)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deviceID := reading.DeviceID
+	if !reading.Envelope {
+		// Flat telemetry: prefer the DeviceID from Context (Auth Token) over the body
 		if idVal, exists := ctx.Get("user_id"); exists {
 			// Handle potential types for user_id from JWT
 			switch v := idVal.(type) {
 			case string:
-				deviceID, _ = uuid.Parse(v)
+				if id, err := uuid.Parse(v); err == nil {
+					deviceID = id
+				}
 			case uuid.UUID:
 				deviceID = v
 			}
 		}
+	}
 
-		// If still no DeviceID, check if it's in the flat JSON
-		var flatMap map[string]interface{}
-		if err := json.Unmarshal(bodyBytes, &flatMap); err == nil {
-			if idStr, ok := flatMap["device_id"].(string); ok && deviceID == uuid.Nil {
-				deviceID, _ = uuid.Parse(idStr)
-			}
-		}
-
-		if deviceID == uuid.Nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Device ID required (in token or body)"})
-			return
-		}
-
-		sensorType = "telemetry" // Default for flat JSON
-		payload = bodyBytes      // The whole body is the payload
+	if deviceID == uuid.Nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Device ID required (in token or body)"})
+		return
 	}
+	sensorType := reading.SensorType
+	payload := reading.Payload
 
-	alerts, err := c.SensorUseCase.ProcessSensorData(deviceID, sensorType, payload)
+	// Enforce the per-device upload budget before touching the queue
+	if ok, wait := c.QuotaUseCase.AllowTelemetry(deviceID); !ok {
+		rejectOverQuota(ctx, deviceID, wait)
+		return
+	}
+	// Refuse unknown devices now rather than accept a reading that cannot be stored
+	exists, err := c.DeviceUseCase.Exists(ctx.Request.Context(), deviceID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process data"})
+		respondError(ctx, err, http.StatusServiceUnavailable, "Unable to verify device")
+		return
+	}
+	if !exists {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
 		return
 	}
 
-	// Broadcast to WebSocket clients
-	c.Hub.BroadcastData(gin.H{
-		"type":        "sensor_update",
-		"device_id":   deviceID.String(),
-		"sensor_type": sensorType,
-		"payload":     payload,
-		"timestamp":   time.Now(),
+	// Hand off to the ingestion pipeline; persistence and hazard checks happen asynchronously
+	err = c.IngestionUseCase.Enqueue(usecases.SensorJob{
+		DeviceID:   deviceID,
+		SensorType: sensorType,
+		Payload:    payload,
+		ReceivedAt: reading.Time(time.Now(), usecases.MaxBackdate),
 	})
+	if err != nil {
+		if errors.Is(err, usecases.ErrIngestQueueFull) {
+			ctx.Header("Retry-After", "1")
+			ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "Ingestion queue is full, retry later"})
+			return
+		}
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Ingestion is temporarily unavailable"})
+		return
+	}
 
-	// Broadcast Alerts if any
-	for _, alert := range alerts {
-		c.Hub.BroadcastData(gin.H{
-			"type":  "alert",
-			"alert": alert,
+	state := telemetry.CommandState{Buzzer: c.DeviceUseCase.BuzzerState(ctx.Request.Context(), deviceID)}
+	if format == telemetry.FormatJSON {
+		ctx.JSON(http.StatusAccepted, gin.H{
+			"message": "Data accepted for processing",
+			"buzzer":  state.Buzzer,
 		})
+		return
 	}
 
-	// [Modified] Check Buzzer State
-	var buzzerState bool = false
-	if device, err := c.DeviceUseCase.GetDeviceByID(deviceID); err == nil {
-		buzzerState = device.BuzzerActive
+	// Binary clients get a compact reply in the format they sent
+	body, err := telemetry.EncodeCommandState(format, state)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
 	}
-
-	ctx.JSON(http.StatusOK, gin.H{
-		"message":          "Data received successfully",
-		"alerts_generated": len(alerts),
-		"buzzer":           buzzerState,
-	})
+	ctx.Data(http.StatusAccepted, format, body)
 }
 
 func (c *SensorController) GetLatest(ctx *gin.Context) {
@@ -129,9 +131,9 @@ func (c *SensorController) GetLatest(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
 		return
 	}
-	reading, err := c.SensorUseCase.GetLatest(deviceID)
+	reading, err := c.SensorUseCase.GetLatest(ctx.Request.Context(), deviceID)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "No data found"})
+		respondError(ctx, err, http.StatusNotFound, "No data found")
 		return
 	}
 	ctx.JSON(http.StatusOK, reading)
@@ -146,14 +148,98 @@ func (c *SensorController) GetHistory(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
 		return
 	}
-	readings, err := c.SensorUseCase.GetHistory(deviceID, start, end)
+	readings, err := c.SensorUseCase.GetHistory(ctx.Request.Context(), deviceID, start, end)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+		if errors.Is(err, usecases.ErrInvalidStartTime) || errors.Is(err, usecases.ErrInvalidEndTime) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(ctx, err, http.StatusInternalServerError, "Failed to fetch history")
 		return
 	}
 	ctx.JSON(http.StatusOK, readings)
 }
 
+// GetAggregate returns min/max/avg/last/count per metric per time bucket.
+// Devices are selected with device_id, a comma-separated device_ids list, or a zone (device location).
+func (c *SensorController) GetAggregate(ctx *gin.Context) {
+	var deviceIDs []uuid.UUID
+	for _, raw := range append([]string{ctx.Query("device_id")}, strings.Split(ctx.Query("device_ids"), ",")...) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+			return
+		}
+		deviceIDs = append(deviceIDs, id)
+	}
+
+	zone := ctx.Query("zone")
+	if zone != "" {
+		devices, err := c.DeviceUseCase.GetDevicesByZone(ctx.Request.Context(), zone)
+		if err != nil {
+			respondError(ctx, err, http.StatusInternalServerError, "Failed to resolve zone")
+			return
+		}
+		for _, d := range devices {
+			deviceIDs = append(deviceIDs, d.ID)
+		}
+	}
+
+	var start, end time.Time
+	var err error
+	if v := ctx.Query("start"); v != "" {
+		if start, err = time.Parse(time.RFC3339, v); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start time, expected RFC3339"})
+			return
+		}
+	}
+	if v := ctx.Query("end"); v != "" {
+		if end, err = time.Parse(time.RFC3339, v); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end time, expected RFC3339"})
+			return
+		}
+	}
+
+	var metrics []string
+	if v := ctx.Query("metrics"); v != "" {
+		for _, m := range strings.Split(v, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				metrics = append(metrics, m)
+			}
+		}
+	}
+
+	aggregates, err := c.SensorUseCase.Aggregate(ctx.Request.Context(), deviceIDs, ctx.DefaultQuery("bucket", "5m"), start, end, metrics)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecases.ErrInvalidBucket), errors.Is(err, usecases.ErrRangeTooLarge),
+			errors.Is(err, usecases.ErrInvalidTimeRange):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, usecases.ErrNoDevices) && zone != "":
+			// A zone with no devices has no history
+			ctx.JSON(http.StatusOK, []entities.SensorAggregate{})
+		case errors.Is(err, usecases.ErrNoDevices):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "device_id, device_ids or zone is required"})
+		default:
+			respondError(ctx, err, http.StatusInternalServerError, "Failed to aggregate history")
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, aggregates)
+}
+
 func (c *SensorController) ServeWS(ctx *gin.Context) {
 	c.Hub.HandleWebSocket(ctx)
 }
+
+func (c *SensorController) ServeEvents(ctx *gin.Context) {
+	c.Hub.HandleSSE(ctx)
+}
+
+func (c *SensorController) ServeEventSchema(ctx *gin.Context) {
+	websocket.ServeSchema(ctx)
+}
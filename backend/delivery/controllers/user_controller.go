@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"errors"
+	"minesense-backend/domain/interfaces"
 	"minesense-backend/infrastructure/utils"
 	"minesense-backend/usecases"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type UserController struct {
@@ -23,9 +26,10 @@ func NewUserController(uc *usecases.UserUseCase, secret string) *UserController
 type RegisterInput struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
-	Role     string `json:"role" binding:"required"`
 }
 
+// Register creates the first account on a fresh install, as an Admin. Afterwards it is closed
+// and Admins create accounts with CreateUser.
 func (c *UserController) Register(ctx *gin.Context) {
 	var input RegisterInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -33,15 +37,31 @@ func (c *UserController) Register(ctx *gin.Context) {
 		return
 	}
 
-	err := c.UserUseCase.Register(input.Username, input.Password, input.Role)
+	user, err := c.UserUseCase.Register(ctx.Request.Context(), input.Username, input.Password)
 	if err != nil {
-		// Return the actual error message to help with debugging (e.g., "duplicate key value violates unique constraint")
-		// In a production app, you might want to map this to a user-friendly message
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondUserError(ctx, err, "Failed to register")
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Admin account created", "user": user})
+}
+
+// respondUserError maps account errors to their status codes.
+func respondUserError(ctx *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, usecases.ErrRegistrationClosed):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, usecases.ErrInvalidRole), errors.Is(err, usecases.ErrWeakPassword):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecases.ErrLastAdmin):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, interfaces.ErrDuplicate):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Username already taken"})
+	case errors.Is(err, interfaces.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	default:
+		respondError(ctx, err, http.StatusInternalServerError, message)
+	}
 }
 
 type LoginInput struct {
@@ -56,9 +76,13 @@ func (c *UserController) Login(ctx *gin.Context) {
 		return
 	}
 
-	user, err := c.UserUseCase.Login(input.Username, input.Password)
+	user, err := c.UserUseCase.Login(ctx.Request.Context(), input.Username, input.Password)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		if errors.Is(err, usecases.ErrAccountDisabled) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+			return
+		}
+		respondError(ctx, err, http.StatusUnauthorized, "Invalid credentials")
 		return
 	}
 
@@ -86,22 +110,121 @@ func (c *UserController) ChangePassword(ctx *gin.Context) {
 		return
 	}
 
-	// Get username from context (set by AuthMiddleware)
-	username, exists := ctx.Get("username")
-	if !exists {
+	// Get the user from context (set by AuthMiddleware)
+	userID, err := uuid.Parse(ctx.GetString("user_id"))
+	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	err := c.UserUseCase.ChangePassword(username.(string), input.OldPassword, input.NewPassword)
+	err = c.UserUseCase.ChangePassword(ctx.Request.Context(), userID, input.OldPassword, input.NewPassword)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if err.Error() == "invalid old password" {
 			status = http.StatusUnauthorized
+		} else if errors.Is(err, usecases.ErrWeakPassword) {
+			status = http.StatusBadRequest
 		}
-		ctx.JSON(status, gin.H{"error": err.Error()})
+		respondError(ctx, err, status, err.Error())
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
 }
+
+type CreateUserInput struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role" binding:"required"` // Admin, Supervisor, Worker
+}
+
+// CreateUser creates an account (Admin only).
+func (c *UserController) CreateUser(ctx *gin.Context) {
+	var input CreateUserInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := c.UserUseCase.CreateUser(ctx.Request.Context(), input.Username, input.Password, input.Role)
+	if err != nil {
+		respondUserError(ctx, err, "Failed to create user")
+		return
+	}
+	ctx.JSON(http.StatusCreated, user)
+}
+
+// GetAllUsers lists every account (Admin only).
+func (c *UserController) GetAllUsers(ctx *gin.Context) {
+	users, err := c.UserUseCase.ListUsers(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+	ctx.JSON(http.StatusOK, users)
+}
+
+type UpdateRoleInput struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdateRole changes a user's role (Admin only).
+func (c *UserController) UpdateRole(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var input UpdateRoleInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := c.UserUseCase.UpdateRole(ctx.Request.Context(), id, input.Role)
+	if err != nil {
+		respondUserError(ctx, err, "Failed to update role")
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+type SetDisabledInput struct {
+	Disabled *bool `json:"disabled" binding:"required"`
+}
+
+// SetDisabled disables or re-enables a user (Admin only).
+func (c *UserController) SetDisabled(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var input SetDisabledInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := c.UserUseCase.SetDisabled(ctx.Request.Context(), id, *input.Disabled)
+	if err != nil {
+		respondUserError(ctx, err, "Failed to update user")
+		return
+	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+// DeleteUser removes a user (Admin only).
+func (c *UserController) DeleteUser(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := c.UserUseCase.DeleteUser(ctx.Request.Context(), id); err != nil {
+		respondUserError(ctx, err, "Failed to delete user")
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -1,42 +1,511 @@
 package controllers
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+	"minesense-backend/infrastructure/mjpeg"
 	"minesense-backend/infrastructure/websocket"
+	"minesense-backend/usecases"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type VideoController struct {
-	Hub *websocket.Hub
+	ImageUseCase     *usecases.ImageUseCase
+	QuotaUseCase     *usecases.QuotaUseCase
+	CameraUseCase    *usecases.CameraUseCase
+	FrameRateUseCase *usecases.FrameRateUseCase
+	Hub              *websocket.Hub
+	Frames           *mjpeg.Broker
 }
 
-func NewVideoController(hub *websocket.Hub) *VideoController {
-	return &VideoController{Hub: hub}
+func NewVideoController(iuc *usecases.ImageUseCase, quc *usecases.QuotaUseCase, cuc *usecases.CameraUseCase, fruc *usecases.FrameRateUseCase, hub *websocket.Hub, frames *mjpeg.Broker) *VideoController {
+	return &VideoController{ImageUseCase: iuc, QuotaUseCase: quc, CameraUseCase: cuc, FrameRateUseCase: fruc, Hub: hub, Frames: frames}
 }
 
 type StreamFrameInput struct {
 	DeviceID string `json:"device_id" binding:"required"`
+	// ImageURL is the frame as a data:image/jpeg;base64,... URI (or bare base64).
 	ImageURL string `json:"image_url" binding:"required"`
 }
 
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// decodeFrame extracts the JPEG bytes from a data URI or a bare base64 string.
+func decodeFrame(uri string) ([]byte, error) {
+	payload := uri
+	if rest, ok := strings.CutPrefix(uri, "data:"); ok {
+		header, data, found := strings.Cut(rest, ",")
+		if !found || !strings.HasSuffix(header, ";base64") {
+			return nil, errors.New("expected a base64 data URI")
+		}
+		if mediaType := strings.TrimSuffix(header, ";base64"); mediaType != "image/jpeg" && mediaType != "image/jpg" {
+			return nil, errUnsupportedMediaType
+		}
+		payload = data
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(payload))
+}
+
+// StreamFrame stores a base64-encoded camera frame and broadcasts its URL.
 func (c *VideoController) StreamFrame(ctx *gin.Context) {
+	// 1. Bound the body: base64 inflates the frame by a third
+	maxBody := c.ImageUseCase.Config.MaxFrameBytes*4/3 + 4096
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBody)
+
 	var input StreamFrameInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": usecases.ErrImageTooLarge.Error()})
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Broadcast to WebSocket clients
-	c.Hub.BroadcastData(gin.H{
-		"type": "image_update",
-		"payload": gin.H{
-			"device_id":  input.DeviceID,
-			"image_url":  input.ImageURL,
-			"created_at": time.Now(),
-		},
-	})
+	deviceID, err := uuid.Parse(input.DeviceID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+	c.received(ctx, deviceID, len(input.ImageURL))
+	if ok, wait := c.QuotaUseCase.AllowFrame(deviceID); !ok {
+		rejectOverQuota(ctx, deviceID, wait)
+		return
+	}
+	if err := c.CameraUseCase.CheckCamera(ctx.Request.Context(), deviceID); err != nil {
+		respondFrameError(ctx, err)
+		return
+	}
+
+	// 2. Decode the frame
+	data, err := decodeFrame(input.ImageURL)
+	if err != nil {
+		if errors.Is(err, errUnsupportedMediaType) {
+			ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Only image/jpeg frames are supported"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image data"})
+		return
+	}
+
+	// 3. Store it and broadcast its URL to real-time clients
+	c.storeFrame(ctx, deviceID, data)
+}
+
+// DeviceIDHeader identifies the camera on binary frame uploads.
+const DeviceIDHeader = "X-Device-ID"
+
+// multipartOverhead allows for boundaries and part headers around an uploaded frame.
+const multipartOverhead = 16 << 10
+
+// UploadFrame stores a camera frame sent as a raw image/jpeg body or as the file part of a
+// multipart/form-data upload, with the device ID in the X-Device-ID header. It avoids the
+// base64 encoding StreamFrame requires, which constrained devices pay for in RAM.
+func (c *VideoController) UploadFrame(ctx *gin.Context) {
+	// 1. Identify the device before reading the body
+	deviceID, err := uuid.Parse(ctx.GetHeader(DeviceIDHeader))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Missing or invalid " + DeviceIDHeader + " header"})
+		return
+	}
+	// Chunked bodies are counted once read below
+	c.received(ctx, deviceID, 0)
+	if ok, wait := c.QuotaUseCase.AllowFrame(deviceID); !ok {
+		rejectOverQuota(ctx, deviceID, wait)
+		return
+	}
+	if err := c.CameraUseCase.CheckCamera(ctx.Request.Context(), deviceID); err != nil {
+		respondFrameError(ctx, err)
+		return
+	}
+
+	// 2. Read the frame, refusing oversized bodies without buffering them
+	maxBytes := c.ImageUseCase.Config.MaxFrameBytes
+	if ctx.Request.ContentLength > maxBytes+multipartOverhead {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": usecases.ErrImageTooLarge.Error()})
+		return
+	}
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBytes+multipartOverhead)
+
+	var data []byte
+	mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+	switch mediaType {
+	case "image/jpeg":
+		data, err = readFrame(ctx.Request.Body, maxBytes)
+	case "multipart/form-data":
+		data, err = readMultipartFrame(ctx.Request, maxBytes)
+	default:
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be image/jpeg or multipart/form-data"})
+		return
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.Is(err, usecases.ErrImageTooLarge), errors.As(err, &tooLarge):
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": usecases.ErrImageTooLarge.Error()})
+		default:
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if ctx.Request.ContentLength < 0 {
+		c.FrameRateUseCase.Received(deviceID, int64(len(data)))
+	}
+
+	// 3. Store it and broadcast its URL to real-time clients
+	c.storeFrame(ctx, deviceID, data)
+}
+
+// readFrame reads at most maxBytes, failing with ErrImageTooLarge beyond that.
+func readFrame(r io.Reader, maxBytes int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, usecases.ErrImageTooLarge
+	}
+	return data, nil
+}
+
+// readMultipartFrame returns the first file part of a multipart upload.
+func readMultipartFrame(req *http.Request, maxBytes int64) ([]byte, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("multipart upload contains no file")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+		if contentType := part.Header.Get("Content-Type"); contentType != "" && contentType != "image/jpeg" && contentType != "application/octet-stream" {
+			return nil, errors.New("file part must be image/jpeg")
+		}
+		return readFrame(part, maxBytes)
+	}
+}
+
+// frameReply is the image record of a stored frame plus the frame rate the camera should send at
+// (0 for no limit).
+type frameReply struct {
+	*entities.Image
+	TargetFPS float64 `json:"target_fps"`
+}
+
+// storeFrame stores a decoded frame and replies with its image record, or drops it with 202
+// when the camera sends faster than its target frame rate.
+func (c *VideoController) storeFrame(ctx *gin.Context, deviceID uuid.UUID, data []byte) {
+	kept, fps, at := c.FrameRateUseCase.Admit(ctx.Request.Context(), deviceID)
+	if !kept {
+		ctx.JSON(http.StatusAccepted, gin.H{"dropped": true, "target_fps": fps})
+		return
+	}
+	image, err := c.ImageUseCase.StoreFrame(ctx.Request.Context(), deviceID, data, time.Now())
+	if err != nil {
+		respondFrameError(ctx, err)
+		return
+	}
+	c.FrameRateUseCase.Accepted(deviceID, len(data), fps, at)
+	ctx.JSON(http.StatusOK, frameReply{Image: image, TargetFPS: fps})
+}
+
+// received counts an upload towards its camera's bandwidth as soon as the camera is known, so
+// uploads refused by the quota or as invalid still count. Bodies of unknown length count as size.
+func (c *VideoController) received(ctx *gin.Context, deviceID uuid.UUID, size int) {
+	n := ctx.Request.ContentLength
+	if n < 0 {
+		n = int64(size)
+	}
+	c.FrameRateUseCase.Received(deviceID, n)
+}
 
+func respondFrameError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecases.ErrImageTooLarge):
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, usecases.ErrInvalidImage):
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, interfaces.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+	case errors.Is(err, usecases.ErrNotCamera):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		respondError(ctx, err, http.StatusInternalServerError, "Failed to store frame")
+	}
+}
+
+// GetImage serves a stored frame. It accepts a signed URL or a JWT.
+func (c *VideoController) GetImage(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+	image, data, err := c.ImageUseCase.GetImageData(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err, http.StatusNotFound, "Image not found")
+		return
+	}
+	// Frames never change once stored
+	ctx.Header("Cache-Control", "private, max-age=86400, immutable")
+	ctx.Data(http.StatusOK, image.ContentType, data)
+}
+
+// GetThumbnail serves a scaled-down copy of a stored frame. It accepts a signed URL or a JWT.
+func (c *VideoController) GetThumbnail(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image ID"})
+		return
+	}
+	_, data, err := c.ImageUseCase.GetThumbnail(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err, http.StatusNotFound, "Image not found")
+		return
+	}
+	ctx.Header("Cache-Control", "private, max-age=86400, immutable")
+	ctx.Data(http.StatusOK, "image/jpeg", data)
+}
+
+// GetLatestImage returns the most recent frame of ?device_id=.
+func (c *VideoController) GetLatestImage(ctx *gin.Context) {
+	deviceID, err := uuid.Parse(ctx.Query("device_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+	image, err := c.ImageUseCase.GetLatestImage(ctx.Request.Context(), deviceID)
+	if err != nil {
+		respondError(ctx, err, http.StatusNotFound, "No image found")
+		return
+	}
+	ctx.JSON(http.StatusOK, image)
+}
+
+// ListDeviceImages returns a device's frames in [start, end] (RFC3339), newest first;
+// ?min_motion= (0-1) keeps only frames with at least that motion score.
+func (c *VideoController) ListDeviceImages(ctx *gin.Context) {
+	deviceID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+	var start, end time.Time
+	if v := ctx.Query("start"); v != "" {
+		if start, err = time.Parse(time.RFC3339, v); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start time, expected RFC3339"})
+			return
+		}
+	}
+	if v := ctx.Query("end"); v != "" {
+		if end, err = time.Parse(time.RFC3339, v); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end time, expected RFC3339"})
+			return
+		}
+	}
+	limit := 0
+	if v := ctx.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+	}
+	minMotion := 0.0
+	if v := ctx.Query("min_motion"); v != "" {
+		if minMotion, err = strconv.ParseFloat(v, 64); err != nil || minMotion < 0 || minMotion > 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_motion, expected 0-1"})
+			return
+		}
+	}
+
+	images, err := c.ImageUseCase.ListImages(ctx.Request.Context(), deviceID, start, end, minMotion, limit)
+	if err != nil {
+		if errors.Is(err, usecases.ErrInvalidTimeRange) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(ctx, err, http.StatusInternalServerError, "Failed to fetch images")
+		return
+	}
+	ctx.JSON(http.StatusOK, images)
+}
+
+// StreamMJPEG serves a camera's live video as multipart/x-mixed-replace, playable in an <img> tag.
+// Every viewer is fed from the frames being uploaded, not from storage.
+func (c *VideoController) StreamMJPEG(ctx *gin.Context) {
+	deviceID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+
+	// 1. Join the camera's feed; a viewer arriving before any frame since startup starts
+	// from the last stored frame
+	viewer, first := c.Frames.Subscribe(deviceID)
+	defer c.Frames.Unsubscribe(viewer)
+	if first == nil {
+		if first, err = c.ImageUseCase.LatestFrame(ctx.Request.Context(), deviceID); err != nil {
+			respondError(ctx, err, http.StatusNotFound, "Device not found")
+			return
+		}
+	}
+
+	// 2. Stream until the viewer disconnects
+	_ = mjpeg.Stream(ctx.Request.Context(), ctx.Writer, viewer, first)
+}
+
+// ExportFrames downloads the device's frames in [start, end] (RFC3339) as a clip:
+// ?format=zip (JPEGs, default), mjpeg (concatenated JPEGs) or avi (Motion-JPEG video).
+// ?interval= (e.g. 5s) keeps frames at least that far apart; ?fps= sets the AVI frame rate.
+func (c *VideoController) ExportFrames(ctx *gin.Context) {
+	// 1. Parse the query
+	deviceID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+	var q usecases.ExportQuery
+	if q.Start, err = time.Parse(time.RFC3339, ctx.Query("start")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing start time, expected RFC3339"})
+		return
+	}
+	if q.End, err = time.Parse(time.RFC3339, ctx.Query("end")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing end time, expected RFC3339"})
+		return
+	}
+	if v := ctx.Query("interval"); v != "" {
+		if q.Interval, err = time.ParseDuration(v); err != nil || q.Interval < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interval, expected a duration such as 5s"})
+			return
+		}
+	}
+	formatName := ctx.DefaultQuery("format", "zip")
+	format, ok := usecases.ExportFormats[formatName]
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": usecases.ErrUnsupportedExportFormat.Error()})
+		return
+	}
+	fps := 5
+	if v := ctx.Query("fps"); v != "" {
+		if fps, err = strconv.Atoi(v); err != nil || fps < 1 || fps > 60 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fps, expected 1-60"})
+			return
+		}
+	}
+
+	// 2. Select the frames before writing anything, so errors still get a JSON response
+	frames, err := c.ImageUseCase.PlanExport(ctx.Request.Context(), deviceID, q)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecases.ErrInvalidTimeRange), errors.Is(err, usecases.ErrExportTooLarge):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			respondError(ctx, err, http.StatusNotFound, "Device not found")
+		}
+		return
+	}
+
+	// 3. Stream the clip; a failure past this point can only cut the download short
+	filename := fmt.Sprintf("%s_%s.%s", deviceID, q.Start.UTC().Format("20060102T150405Z"), format.Extension)
+	ctx.Header("Content-Type", format.ContentType)
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	ctx.Header("X-Frame-Count", strconv.Itoa(len(frames)))
 	ctx.Status(http.StatusOK)
+	if err := c.ImageUseCase.WriteExport(ctx.Request.Context(), ctx.Writer, frames, formatName, fps); err != nil {
+		log.Printf("Export of %s failed: %v", deviceID, err)
+	}
+}
+
+// UpdateCameraSettingsInput replaces a camera's settings; null or omitted fields revert to the
+// server defaults.
+type UpdateCameraSettingsInput struct {
+	// RetentionHours keeps the camera's frames for this many hours (0 keeps them forever).
+	RetentionHours *int `json:"retention_hours"`
+	// RestrictedArea raises an alert when the camera sees motion of at least MotionThreshold (0-1).
+	RestrictedArea  bool     `json:"restricted_area"`
+	MotionThreshold *float64 `json:"motion_threshold"`
+	// TargetFPS is the frame rate the camera is told to send at (up to 30); faster frames are dropped.
+	TargetFPS *float64 `json:"target_fps"`
+}
+
+// GetCameraSettings returns a camera's settings and the values in effect.
+func (c *VideoController) GetCameraSettings(ctx *gin.Context) {
+	deviceID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+	settings, err := c.CameraUseCase.GetSettings(ctx.Request.Context(), deviceID)
+	if err != nil {
+		respondError(ctx, err, http.StatusNotFound, "Device not found")
+		return
+	}
+	ctx.JSON(http.StatusOK, settings)
+}
+
+// UpdateCameraSettings replaces a camera's settings.
+func (c *VideoController) UpdateCameraSettings(ctx *gin.Context) {
+	deviceID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+	var input UpdateCameraSettingsInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	settings, err := c.CameraUseCase.UpdateSettings(ctx.Request.Context(), deviceID, &entities.CameraSettings{
+		RetentionHours:  input.RetentionHours,
+		RestrictedArea:  input.RestrictedArea,
+		MotionThreshold: input.MotionThreshold,
+		TargetFPS:       input.TargetFPS,
+	})
+	if err != nil {
+		if errors.Is(err, usecases.ErrInvalidRetention) || errors.Is(err, usecases.ErrInvalidMotionThreshold) ||
+			errors.Is(err, usecases.ErrInvalidTargetFPS) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		respondError(ctx, err, http.StatusNotFound, "Device not found")
+		return
+	}
+	ctx.JSON(http.StatusOK, settings)
+}
+
+// GetCameraStats returns a camera's frame counts and bandwidth.
+func (c *VideoController) GetCameraStats(ctx *gin.Context) {
+	deviceID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+	stats, err := c.FrameRateUseCase.Stats(ctx.Request.Context(), deviceID)
+	if err != nil {
+		respondError(ctx, err, http.StatusNotFound, "Device not found")
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
 }
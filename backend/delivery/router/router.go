@@ -2,7 +2,9 @@ package router
 
 import (
 	"minesense-backend/delivery/controllers"
+	"minesense-backend/infrastructure/metrics"
 	"minesense-backend/infrastructure/middleware"
+	"minesense-backend/infrastructure/ratelimit"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -15,15 +17,19 @@ func SetupRouter(
 	alertController *controllers.AlertController,
 	userController *controllers.UserController,
 	videoController *controllers.VideoController,
+	ingestLimiter *ratelimit.Limiter,
 	jwtSecret string,
+	metricsToken string,
 ) *gin.Engine {
-	r := gin.Default()
+	// gin.Default without its logger, which would log ?token= credentials
+	r := gin.New()
+	r.Use(middleware.AccessLogger(), gin.Recovery())
 
 	// CORS Configuration
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Device-ID"}
 	config.AllowCredentials = true
 	config.MaxAge = 12 * time.Hour
 
@@ -35,37 +41,71 @@ func SetupRouter(
 		c.JSON(200, gin.H{"status": "ok", "message": "Mining Hazard Detection API is running"})
 	})
 
+	// Prometheus metrics (ingestion queue depth, etc.) for scrapers holding the metrics token.
+	// They name devices, so they are never public; see also METRICS_ADDR
+	if metricsToken != "" {
+		r.GET("/metrics", middleware.StaticTokenMiddleware(metricsToken), metrics.Handler())
+	}
+
+	// Tokens of disabled or deleted users stop working, and role changes apply immediately
+	activeAccount := middleware.ActiveAccountMiddleware(userController.UserUseCase.Account)
+
 	// Public routes
 	api := r.Group("/api/v1")
 	{
 		api.POST("/login", userController.Login)
-		api.POST("/register", userController.Register) // In a real app, this might be protected or admin-only
-		api.GET("/ws", sensorController.ServeWS)       // WebSocket endpoint for real-time updates
+		api.POST("/register", userController.Register)               // First-run only: creates the initial Admin, then closes
+		api.GET("/ws", sensorController.ServeWS)                     // WebSocket endpoint; authenticates the JWT itself (query param or first message)
+		api.GET("/events", sensorController.ServeEvents)             // Server-Sent Events alternative; authenticates the JWT itself (header or query param)
+		api.GET("/events/schema", sensorController.ServeEventSchema) // JSON schema of the real-time event envelope
+
+		// Stored camera frames; signed URLs work without an Authorization header (e.g. <img> tags)
+		api.GET("/images/:id", middleware.SignedURLMiddleware(jwtSecret, activeAccount), videoController.GetImage)
+		api.GET("/images/:id/thumbnail", middleware.SignedURLMiddleware(jwtSecret, activeAccount), videoController.GetThumbnail)
+		// Live MJPEG video; the JWT may be passed as ?token= for <img> tags
+		api.GET("/devices/:id/stream.mjpeg", middleware.QueryTokenAuthMiddleware(jwtSecret), activeAccount, videoController.StreamMJPEG)
 	}
 
 	// Protected routes
 	protected := api.Group("/")
-	protected.Use(middleware.AuthMiddleware(jwtSecret))
+	protected.Use(middleware.AuthMiddleware(jwtSecret), activeAccount)
 	{
 		// Sensor Data
-		protected.POST("/sensor-data", sensorController.ReceiveSensorData)
+		protected.POST("/sensor-data", middleware.RateLimitMiddleware(ingestLimiter), sensorController.ReceiveSensorData)
 		protected.GET("/sensors/latest", sensorController.GetLatest)
 		protected.GET("/sensors/history", sensorController.GetHistory)
+		protected.GET("/sensors/aggregate", sensorController.GetAggregate)
 
 		// Devices (Admin only)
 		protected.POST("/devices", middleware.RoleMiddleware("Admin"), deviceController.CreateDevice)
 		protected.GET("/devices", deviceController.GetAllDevices)
 		protected.GET("/devices/:id", deviceController.GetDeviceByID)
 		protected.POST("/devices/:id/command", deviceController.TriggerBuzzer)
+		protected.GET("/devices/:id/images", videoController.ListDeviceImages)
+		protected.GET("/devices/:id/images/export", videoController.ExportFrames) // ?start&end&format=zip|mjpeg|avi
+		protected.GET("/devices/:id/camera", videoController.GetCameraSettings)
+		protected.PUT("/devices/:id/camera", middleware.RoleMiddleware("Admin"), videoController.UpdateCameraSettings)
+		protected.GET("/devices/:id/camera/stats", videoController.GetCameraStats)
 
 		// Alerts
 		protected.GET("/alerts", alertController.GetAllAlerts)
+		protected.GET("/alerts/:id/evidence", alertController.GetEvidence)
 
 		// User
 		protected.POST("/change-password", userController.ChangePassword)
 
+		// User management (Admin only)
+		admin := protected.Group("/users", middleware.RoleMiddleware("Admin"))
+		admin.GET("", userController.GetAllUsers)
+		admin.POST("", userController.CreateUser)
+		admin.PUT("/:id/role", userController.UpdateRole)
+		admin.PUT("/:id/disabled", userController.SetDisabled)
+		admin.DELETE("/:id", userController.DeleteUser)
+
 		// Video Stream
-		protected.POST("/images/stream", videoController.StreamFrame)
+		protected.POST("/images/stream", middleware.RateLimitMiddleware(ingestLimiter), videoController.StreamFrame)
+		protected.POST("/images/upload", middleware.RateLimitMiddleware(ingestLimiter), videoController.UploadFrame) // Raw JPEG or multipart, device in X-Device-ID
+		protected.GET("/images/latest", videoController.GetLatestImage)
 	}
 
 	return r
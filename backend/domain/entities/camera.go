@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CameraSettings override how a camera's frames are handled; nil fields fall back to the
+// server-wide defaults.
+type CameraSettings struct {
+	DeviceID uuid.UUID `gorm:"type:uuid;primaryKey" json:"device_id"`
+	// RetentionHours is how long frames are kept (0 keeps them forever).
+	RetentionHours *int `json:"retention_hours"`
+	// RestrictedArea raises an alert when motion is detected; MotionThreshold (0-1) is the
+	// motion score that counts as motion.
+	RestrictedArea  bool     `gorm:"not null;default:false" json:"restricted_area"`
+	MotionThreshold *float64 `json:"motion_threshold"`
+	// TargetFPS is the frame rate the camera is asked to send; faster frames are dropped.
+	TargetFPS *float64  `json:"target_fps"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (CameraSettings) TableName() string { return "camera_settings" }
@@ -12,6 +12,8 @@ type Device struct {
 	Location     string    `json:"location"`
 	SupervisorID *uuid.UUID `gorm:"type:uuid" json:"supervisor_id"` // Pointer to allow null
 	Supervisor   *User      `gorm:"foreignKey:SupervisorID" json:"supervisor,omitempty"`
+	WorkerID     *uuid.UUID `gorm:"type:uuid" json:"worker_id"` // Worker wearing the device, if any
+	HasCamera    bool       `gorm:"not null;default:false" json:"has_camera"` // May upload camera frames
 	BuzzerActive bool       `gorm:"default:false" json:"buzzer_active"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
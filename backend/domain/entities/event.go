@@ -0,0 +1,97 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventProtocolVersion is the version of the real-time envelope below. Version 1 is the
+// earlier ad-hoc format, still served to clients that do not negotiate a version.
+const EventProtocolVersion = 2
+
+// Real-time event types. The JSON schema of each is published at /api/v1/events/schema.
+const (
+	EventSensorUpdate  = "sensor_update"
+	EventAlert         = "alert"
+	EventImageUpdate   = "image_update"
+	EventDeviceCommand = "device_command"
+)
+
+// Event is the envelope of every real-time message pushed over WebSocket and SSE.
+// The hub adds a per-instance "seq" when it delivers the event.
+type Event struct {
+	Type      string      `json:"type"`
+	Version   int         `json:"version"`
+	ID        uuid.UUID   `json:"id"`
+	Timestamp time.Time   `json:"timestamp"`
+	DeviceID  string      `json:"device_id,omitempty"`
+	Payload   interface{} `json:"payload"`
+}
+
+type SensorUpdatePayload struct {
+	SensorType string          `json:"sensor_type"`
+	Readings   json.RawMessage `json:"readings"`
+}
+
+type ImageUpdatePayload struct {
+	ImageID   string `json:"image_id"`
+	ImageURL  string `json:"image_url"`
+	SizeBytes int64  `json:"size_bytes"`
+	// MotionScore is set when the frame was compared with the camera's previous one.
+	MotionScore *float64 `json:"motion_score,omitempty"`
+}
+
+type DeviceCommandPayload struct {
+	Command  string `json:"command"`
+	IsActive bool   `json:"is_active"`
+	// TargetFPS is the frame rate a "set_fps" command asks a camera to send.
+	TargetFPS float64 `json:"target_fps,omitempty"`
+}
+
+func NewEvent(eventType string, deviceID uuid.UUID, timestamp time.Time, payload interface{}) Event {
+	return Event{
+		Type:      eventType,
+		Version:   EventProtocolVersion,
+		ID:        uuid.New(),
+		Timestamp: timestamp,
+		DeviceID:  deviceID.String(),
+		Payload:   payload,
+	}
+}
+
+func NewSensorUpdateEvent(deviceID uuid.UUID, sensorType string, readings json.RawMessage, receivedAt time.Time) Event {
+	return NewEvent(EventSensorUpdate, deviceID, receivedAt, SensorUpdatePayload{SensorType: sensorType, Readings: readings})
+}
+
+// NewAlertEvent carries the alert itself as the payload and reuses its ID, so clients can
+// recognise an alert replayed after a reconnect.
+func NewAlertEvent(alert *Alert) Event {
+	event := NewEvent(EventAlert, alert.DeviceID, alert.CreatedAt, alert)
+	if alert.ID != uuid.Nil {
+		event.ID = alert.ID
+	}
+	return event
+}
+
+// NewImageUpdateEvent announces a stored frame; image.ImageURL must already be set.
+func NewImageUpdateEvent(image *Image) Event {
+	event := NewEvent(EventImageUpdate, image.DeviceID, image.Timestamp, ImageUpdatePayload{
+		ImageID:     image.ID.String(),
+		ImageURL:    image.ImageURL,
+		SizeBytes:   image.SizeBytes,
+		MotionScore: image.MotionScore,
+	})
+	event.ID = image.ID
+	return event
+}
+
+func NewDeviceCommandEvent(deviceID uuid.UUID, command string, isActive bool) Event {
+	return NewEvent(EventDeviceCommand, deviceID, time.Now(), DeviceCommandPayload{Command: command, IsActive: isActive})
+}
+
+// NewTargetFPSCommandEvent tells a camera the frame rate to send at.
+func NewTargetFPSCommandEvent(deviceID uuid.UUID, targetFPS float64) Event {
+	return NewEvent(EventDeviceCommand, deviceID, time.Now(), DeviceCommandPayload{Command: "set_fps", IsActive: true, TargetFPS: targetFPS})
+}
@@ -0,0 +1,42 @@
+package entities
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Image is a camera frame. The JPEG itself lives in the blob store under StorageKey;
+// ImageURL and ThumbnailURL are filled in when the frame is served and are never persisted.
+type Image struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	DeviceID    uuid.UUID `gorm:"type:uuid;not null" json:"device_id"`
+	StorageKey  string    `gorm:"not null" json:"-"`
+	ContentType string    `gorm:"not null" json:"content_type"`
+	SizeBytes   int64     `gorm:"not null" json:"size_bytes"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Timestamp   time.Time `gorm:"not null" json:"timestamp"`
+	// MotionScore is the fraction of the scene that changed since the camera's previous frame
+	// (0-1, nil for a first frame); Brightness is the mean luminance (0-1).
+	MotionScore  *float64 `json:"motion_score"`
+	Brightness   *float64 `json:"brightness"`
+	ImageURL     string   `gorm:"-" json:"image_url,omitempty"`
+	ThumbnailURL string   `gorm:"-" json:"thumbnail_url,omitempty"`
+}
+
+// ThumbnailKey is where the frame's thumbnail is cached in the blob store.
+func (i *Image) ThumbnailKey() string {
+	return strings.TrimSuffix(i.StorageKey, ".jpg") + ".thumb.jpg"
+}
+
+// AlertEvidence links an alert to a frame captured around it. Linked frames are kept
+// regardless of image retention.
+type AlertEvidence struct {
+	AlertID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"alert_id"`
+	ImageID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"image_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (AlertEvidence) TableName() string { return "alert_evidence" }
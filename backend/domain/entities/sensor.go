@@ -15,3 +15,48 @@ type SensorReading struct {
 	Timestamp  time.Time       `json:"timestamp"`
 	Device     Device          `gorm:"foreignKey:DeviceID" json:"-"`
 }
+
+// SensorAggregate summarises one numeric payload metric for a device over a time bucket.
+type SensorAggregate struct {
+	DeviceID uuid.UUID `json:"device_id"`
+	Bucket   time.Time `json:"bucket"`
+	Metric   string    `json:"metric"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+	Avg      float64   `json:"avg"`
+	Last     float64   `json:"last"`
+	Count    int64     `json:"count"`
+}
+
+// SensorAggregateQuery selects which readings are aggregated and how.
+type SensorAggregateQuery struct {
+	DeviceIDs []uuid.UUID
+	Bucket    time.Duration
+	Start     time.Time
+	End       time.Time
+	Metrics   []string // empty means every numeric/boolean payload key
+}
+
+// Rollup resolutions for pre-aggregated sensor data.
+const (
+	RollupMinute = "1m"
+	RollupHour   = "1h"
+)
+
+// SensorRollup is a pre-aggregated metric for one device over a minute or hour bucket.
+// Rows live in sensor_rollups_1m and sensor_rollups_1h; Sum is kept so averages can be re-combined.
+type SensorRollup struct {
+	DeviceID uuid.UUID `gorm:"type:uuid;primaryKey" json:"device_id"`
+	Bucket   time.Time `gorm:"primaryKey" json:"bucket"`
+	Metric   string    `gorm:"primaryKey" json:"metric"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+	Sum      float64   `json:"sum"`
+	Last     float64   `json:"last"`
+	Count    int64     `json:"count"`
+}
+
+// RollupTable returns the table holding rollups of the given resolution.
+func RollupTable(resolution string) string {
+	return "sensor_rollups_" + resolution
+}
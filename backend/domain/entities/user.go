@@ -6,11 +6,24 @@ import (
 	"github.com/google/uuid"
 )
 
+// User roles.
+const (
+	RoleAdmin      = "Admin"
+	RoleSupervisor = "Supervisor"
+	RoleWorker     = "Worker"
+)
+
+// ValidRole reports whether role is one of the user roles.
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleSupervisor || role == RoleWorker
+}
+
 type User struct {
 	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	Username  string    `gorm:"unique;not null" json:"username"`
 	Password  string    `gorm:"not null" json:"-"`
 	Role      string    `gorm:"not null" json:"role"` // Admin, Supervisor, Worker
+	Disabled  bool      `gorm:"not null;default:false" json:"disabled"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
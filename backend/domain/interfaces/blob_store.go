@@ -0,0 +1,11 @@
+package interfaces
+
+import "context"
+
+// BlobStore keeps binary objects such as camera frames outside the database, addressed by
+// slash-separated keys. Get returns ErrNotFound for a missing key; Delete of a missing key succeeds.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"minesense-backend/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// Broadcaster pushes real-time events to connected clients (implemented by websocket.Hub).
+type Broadcaster interface {
+	BroadcastData(event entities.Event)
+}
+
+// FrameBroadcaster pushes JPEG frames to live video viewers of a camera (implemented by mjpeg.Broker).
+type FrameBroadcaster interface {
+	PublishFrame(deviceID uuid.UUID, frame []byte)
+}
@@ -0,0 +1,12 @@
+package interfaces
+
+import "errors"
+
+// Repository implementations wrap driver errors in these so callers can map them without
+// depending on a specific database.
+var (
+	ErrQueryTimeout  = errors.New("database query timed out")
+	ErrQueryCanceled = errors.New("database query canceled")
+	ErrNotFound      = errors.New("record not found")
+	ErrDuplicate     = errors.New("record already exists")
+)
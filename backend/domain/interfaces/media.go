@@ -0,0 +1,48 @@
+package interfaces
+
+import (
+	"io"
+	"time"
+)
+
+// ImageProcessor inspects and scales JPEG frames (implemented by imaging.Processor).
+type ImageProcessor interface {
+	// Dimensions returns the size of a JPEG, or an error if data is not a JPEG.
+	Dimensions(data []byte) (width, height int, err error)
+	// Thumbnail scales a JPEG down to width pixels wide, keeping the aspect ratio.
+	Thumbnail(data []byte, width int) ([]byte, error)
+}
+
+// URLSigner signs paths so they can be fetched without an Authorization header until they
+// expire (implemented by utils.URLSigner).
+type URLSigner interface {
+	SignPath(path string, expires time.Time) string
+}
+
+// ClipEncoder starts video clips built from JPEG frames (implemented by mjpeg.AVIEncoder).
+// The container header is only complete once every frame is written, hence an io.WriteSeeker.
+type ClipEncoder interface {
+	NewClip(w io.WriteSeeker, width, height, fps int) (ClipWriter, error)
+}
+
+// ClipWriter appends JPEG frames to a clip; Close finishes it.
+type ClipWriter interface {
+	WriteFrame(jpeg []byte) error
+	Close() error
+}
+
+// SceneAnalyzer summarizes camera frames for motion and obstruction detection (implemented by
+// imaging.SceneAnalyzer).
+type SceneAnalyzer interface {
+	AnalyzeScene(data []byte) (Scene, error)
+}
+
+// Scene summarizes one frame so it can be compared with earlier frames of the same camera.
+type Scene interface {
+	// Brightness is the mean luminance (0-1); Contrast is the spread of luminance across the
+	// frame (0-1), near zero when the lens is covered.
+	Brightness() float64
+	Contrast() float64
+	// Difference is the fraction of the scene (0-1) that changed since prev.
+	Difference(prev Scene) float64
+}
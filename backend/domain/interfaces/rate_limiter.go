@@ -0,0 +1,9 @@
+package interfaces
+
+import "time"
+
+// RateLimiter grants requests against a per-key budget (implemented by ratelimit.Limiter).
+// When a request is refused, the duration says how long until the key may retry.
+type RateLimiter interface {
+	Allow(key string) (bool, time.Duration)
+}
@@ -1,33 +1,108 @@
 package interfaces
 
 import (
+	"context"
 	"minesense-backend/domain/entities"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type DeviceRepository interface {
-	Create(device *entities.Device) error
-	FindByID(id uuid.UUID) (*entities.Device, error)
-	FindAll() ([]entities.Device, error)
-	Update(device *entities.Device) error
+	Create(ctx context.Context, device *entities.Device) error
+	FindByID(ctx context.Context, id uuid.UUID) (*entities.Device, error)
+	FindAll(ctx context.Context) ([]entities.Device, error)
+	FindByLocation(ctx context.Context, location string) ([]entities.Device, error)
+	Update(ctx context.Context, device *entities.Device) error
 }
 
 type SensorRepository interface {
-	Create(reading *entities.SensorReading) error
-	FindByDeviceID(deviceID uuid.UUID) ([]entities.SensorReading, error)
-	GetLatestByDeviceID(deviceID uuid.UUID) (*entities.SensorReading, error)
-	GetHistory(deviceID uuid.UUID, start, end string) ([]entities.SensorReading, error)
+	Create(ctx context.Context, reading *entities.SensorReading) error
+	FindByDeviceID(ctx context.Context, deviceID uuid.UUID) ([]entities.SensorReading, error)
+	GetLatestByDeviceID(ctx context.Context, deviceID uuid.UUID) (*entities.SensorReading, error)
+	GetHistory(ctx context.Context, deviceID uuid.UUID, start, end string) ([]entities.SensorReading, error)
+	Aggregate(ctx context.Context, query entities.SensorAggregateQuery) ([]entities.SensorAggregate, error)
+}
+
+type SensorRollupRepository interface {
+	// RollupRaw folds raw readings in [from, to) into minute rollups.
+	RollupRaw(ctx context.Context, from, to time.Time) (int64, error)
+	// RollupMinutes folds minute rollups in [from, to) into hour rollups.
+	RollupMinutes(ctx context.Context, from, to time.Time) (int64, error)
+	// Watermark returns the newest bucket present at the given resolution (zero if none).
+	Watermark(ctx context.Context, resolution string) (time.Time, error)
+	// OldestRawTimestamp returns the oldest raw reading timestamp at or after `after` (zero if none).
+	OldestRawTimestamp(ctx context.Context, after time.Time) (time.Time, error)
+	// OldestRollup returns the oldest bucket at or after `after` for the given resolution (zero if none).
+	OldestRollup(ctx context.Context, resolution string, after time.Time) (time.Time, error)
+	DeleteRawBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error)
+	DeleteRollupsBefore(ctx context.Context, resolution string, cutoff time.Time, batchSize int) (int64, error)
+	Aggregate(ctx context.Context, resolution string, query entities.SensorAggregateQuery) ([]entities.SensorAggregate, error)
+}
+
+type PartitionRepository interface {
+	// EnsureMonthlyPartitions creates the partition for `from`'s month and the next monthsAhead months.
+	EnsureMonthlyPartitions(ctx context.Context, table string, from time.Time, monthsAhead int) error
+	// DropPartitionsBefore drops partitions entirely older than cutoff and returns their names.
+	DropPartitionsBefore(ctx context.Context, table string, cutoff time.Time) ([]string, error)
 }
 
 type AlertRepository interface {
-	Create(alert *entities.Alert) error
-	FindAll() ([]entities.Alert, error)
-	FindByDeviceID(deviceID uuid.UUID) ([]entities.Alert, error)
+	Create(ctx context.Context, alert *entities.Alert) error
+	FindAll(ctx context.Context) ([]entities.Alert, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*entities.Alert, error)
+	FindByDeviceID(ctx context.Context, deviceID uuid.UUID) ([]entities.Alert, error)
+	// FindBetween returns alerts created in (since, until], oldest first.
+	FindBetween(ctx context.Context, since, until time.Time) ([]entities.Alert, error)
+	// DeleteBefore removes alerts created before cutoff in batches of batchSize.
+	DeleteBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error)
+}
+
+type ImageRepository interface {
+	Create(ctx context.Context, image *entities.Image) error
+	FindByID(ctx context.Context, id uuid.UUID) (*entities.Image, error)
+	// FindByDevice returns the device's frames taken in [start, end], newest first.
+	FindByDevice(ctx context.Context, deviceID uuid.UUID, start, end time.Time, limit int) ([]entities.Image, error)
+	// FindMotion returns the device's frames taken in [start, end] with a motion score of at
+	// least minScore, newest first.
+	FindMotion(ctx context.Context, deviceID uuid.UUID, start, end time.Time, minScore float64, limit int) ([]entities.Image, error)
+	// Usage returns how many frames of the device are stored and their total size in bytes.
+	Usage(ctx context.Context, deviceID uuid.UUID) (frames int64, bytes int64, err error)
+	// LinkToAlert records frames as evidence of an alert; existing links are left as they are.
+	LinkToAlert(ctx context.Context, alertID uuid.UUID, imageIDs []uuid.UUID) error
+	// FindByAlert returns the frames linked to an alert, oldest first.
+	FindByAlert(ctx context.Context, alertID uuid.UUID) ([]entities.Image, error)
+	// ScanByDevice returns up to limit of the device's frames taken in [from, end], oldest first,
+	// for paging forward through a range.
+	ScanByDevice(ctx context.Context, deviceID uuid.UUID, from, end time.Time, limit int) ([]entities.Image, error)
+	// FindExpired returns up to limit of the device's frames taken before cutoff, oldest first,
+	// leaving out frames linked to an alert.
+	FindExpired(ctx context.Context, deviceID uuid.UUID, cutoff time.Time, limit int) ([]entities.Image, error)
+	Delete(ctx context.Context, ids []uuid.UUID) (int64, error)
+}
+
+type CameraSettingsRepository interface {
+	// FindByDeviceID returns ErrNotFound for a camera without settings.
+	FindByDeviceID(ctx context.Context, deviceID uuid.UUID) (*entities.CameraSettings, error)
+	FindAll(ctx context.Context) ([]entities.CameraSettings, error)
+	// Save creates or replaces the camera's settings.
+	Save(ctx context.Context, settings *entities.CameraSettings) error
 }
 
 type UserRepository interface {
-	Create(user *entities.User) error
-	FindByUsername(username string) (*entities.User, error)
-	Update(user *entities.User) error
+	Create(ctx context.Context, user *entities.User) error
+	// CreateFirst creates the user only if there are no users yet, atomically across instances,
+	// and reports whether it did.
+	CreateFirst(ctx context.Context, user *entities.User) (bool, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*entities.User, error)
+	FindByUsername(ctx context.Context, username string) (*entities.User, error)
+	// FindAll returns every user, ordered by username.
+	FindAll(ctx context.Context) ([]entities.User, error)
+	Count(ctx context.Context) (int64, error)
+	Update(ctx context.Context, user *entities.User) error
+	// UpdateKeepingAdmin saves the user unless that would leave no enabled Admin, and
+	// DeleteKeepingAdmin removes it, unassigning it from its devices, under the same condition.
+	// Both are atomic across instances and report whether they made the change.
+	UpdateKeepingAdmin(ctx context.Context, user *entities.User) (bool, error)
+	DeleteKeepingAdmin(ctx context.Context, id uuid.UUID) (bool, error)
 }
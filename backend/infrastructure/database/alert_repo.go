@@ -1,6 +1,9 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"minesense-backend/domain/entities"
 	"minesense-backend/domain/interfaces"
 
@@ -16,18 +19,63 @@ func NewAlertRepo(db *gorm.DB) interfaces.AlertRepository {
 	return &AlertRepo{DB: db}
 }
 
-func (r *AlertRepo) Create(alert *entities.Alert) error {
-	return r.DB.Create(alert).Error
+func (r *AlertRepo) Create(ctx context.Context, alert *entities.Alert) error {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	return mapError(db.Create(alert).Error)
+}
+
+func (r *AlertRepo) FindAll(ctx context.Context) ([]entities.Alert, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var alerts []entities.Alert
+	err := db.Preload("Device").Find(&alerts).Error
+	return alerts, mapError(err)
+}
+
+func (r *AlertRepo) FindByID(ctx context.Context, id uuid.UUID) (*entities.Alert, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var alert entities.Alert
+	err := db.Preload("Device").First(&alert, "id = ?", id).Error
+	return &alert, mapError(err)
 }
 
-func (r *AlertRepo) FindAll() ([]entities.Alert, error) {
+func (r *AlertRepo) FindByDeviceID(ctx context.Context, deviceID uuid.UUID) ([]entities.Alert, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
 	var alerts []entities.Alert
-	err := r.DB.Preload("Device").Find(&alerts).Error
-	return alerts, err
+	err := db.Where("device_id = ?", deviceID).Preload("Device").Find(&alerts).Error
+	return alerts, mapError(err)
 }
 
-func (r *AlertRepo) FindByDeviceID(deviceID uuid.UUID) ([]entities.Alert, error) {
+func (r *AlertRepo) FindBetween(ctx context.Context, since, until time.Time) ([]entities.Alert, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
 	var alerts []entities.Alert
-	err := r.DB.Where("device_id = ?", deviceID).Preload("Device").Find(&alerts).Error
-	return alerts, err
+	// UTC keeps the comparison chronological on SQLite, where times are stored as text
+	err := db.Where("created_at > ? AND created_at <= ?", since.UTC(), until.UTC()).
+		Order("created_at asc").Preload("Device").Find(&alerts).Error
+	return alerts, mapError(err)
+}
+
+// DeleteBefore removes alerts left over by partition drops (the rest of a partly expired month,
+// or every expired alert on SQLite) in batches, so no single statement holds long locks.
+func (r *AlertRepo) DeleteBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		db, cancel := session(r.DB, ctx)
+		result := db.Exec(`
+DELETE FROM alerts
+WHERE id IN (SELECT id FROM alerts WHERE created_at < @cutoff LIMIT @batch)`,
+			map[string]interface{}{"cutoff": cutoff.UTC(), "batch": batchSize})
+		cancel()
+		if result.Error != nil {
+			return total, mapError(result.Error)
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return total, nil
+		}
+	}
 }
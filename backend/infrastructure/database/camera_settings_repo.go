@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CameraSettingsRepo struct {
+	DB *gorm.DB
+}
+
+func NewCameraSettingsRepo(db *gorm.DB) interfaces.CameraSettingsRepository {
+	return &CameraSettingsRepo{DB: db}
+}
+
+func (r *CameraSettingsRepo) FindByDeviceID(ctx context.Context, deviceID uuid.UUID) (*entities.CameraSettings, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var settings entities.CameraSettings
+	err := db.First(&settings, "device_id = ?", deviceID).Error
+	return &settings, mapError(err)
+}
+
+func (r *CameraSettingsRepo) FindAll(ctx context.Context) ([]entities.CameraSettings, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var settings []entities.CameraSettings
+	err := db.Find(&settings).Error
+	return settings, mapError(err)
+}
+
+func (r *CameraSettingsRepo) Save(ctx context.Context, settings *entities.CameraSettings) error {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	settings.UpdatedAt = time.Now().UTC()
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "device_id"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+	return mapError(err)
+}
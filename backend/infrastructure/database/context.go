@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"minesense-backend/domain/interfaces"
+
+	gosqlite "github.com/glebarez/go-sqlite"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// QueryTimeout bounds every repository call on top of the caller's own deadline. Zero disables it.
+var QueryTimeout time.Duration
+
+// pgQueryCanceled is raised when Postgres cancels a statement (statement_timeout or a cancel request).
+const pgQueryCanceled = "57014"
+
+const pgUniqueViolation = "23505"
+
+// session binds db to ctx, applying QueryTimeout. Callers must invoke the returned cancel func.
+func session(db *gorm.DB, ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	if QueryTimeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+		return db.WithContext(ctx), cancel
+	}
+	return db.WithContext(ctx), func() {}
+}
+
+// mapError translates driver errors into the domain errors.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	var liteErr *gosqlite.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %v", interfaces.ErrQueryTimeout, err)
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%w: %v", interfaces.ErrQueryCanceled, err)
+	case errors.As(err, &pgErr) && pgErr.Code == pgQueryCanceled:
+		return fmt.Errorf("%w: %v", interfaces.ErrQueryTimeout, err)
+	case errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation:
+		return fmt.Errorf("%w: %w", interfaces.ErrDuplicate, err)
+	case errors.As(err, &liteErr) && (liteErr.Code() == sqlite3.SQLITE_INTERRUPT || liteErr.Code()&0xff == sqlite3.SQLITE_BUSY):
+		// Interrupted by the query timeout, or the busy_timeout expired waiting for the write lock
+		return fmt.Errorf("%w: %v", interfaces.ErrQueryTimeout, err)
+	case errors.As(err, &liteErr) && (liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY):
+		return fmt.Errorf("%w: %w", interfaces.ErrDuplicate, err)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%w: %w", interfaces.ErrNotFound, err)
+	}
+	return err
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"minesense-backend/domain/entities"
 	"minesense-backend/domain/interfaces"
 
@@ -16,22 +17,38 @@ func NewDeviceRepo(db *gorm.DB) interfaces.DeviceRepository {
 	return &DeviceRepo{DB: db}
 }
 
-func (r *DeviceRepo) Create(device *entities.Device) error {
-	return r.DB.Create(device).Error
+func (r *DeviceRepo) Create(ctx context.Context, device *entities.Device) error {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	return mapError(db.Create(device).Error)
 }
 
-func (r *DeviceRepo) FindByID(id uuid.UUID) (*entities.Device, error) {
+func (r *DeviceRepo) FindByID(ctx context.Context, id uuid.UUID) (*entities.Device, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
 	var device entities.Device
-	err := r.DB.First(&device, "id = ?", id).Error
-	return &device, err
+	err := db.First(&device, "id = ?", id).Error
+	return &device, mapError(err)
 }
 
-func (r *DeviceRepo) FindAll() ([]entities.Device, error) {
+func (r *DeviceRepo) FindAll(ctx context.Context) ([]entities.Device, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
 	var devices []entities.Device
-	err := r.DB.Find(&devices).Error
-	return devices, err
+	err := db.Find(&devices).Error
+	return devices, mapError(err)
 }
 
-func (r *DeviceRepo) Update(device *entities.Device) error {
-	return r.DB.Save(device).Error
+func (r *DeviceRepo) FindByLocation(ctx context.Context, location string) ([]entities.Device, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var devices []entities.Device
+	err := db.Where("location = ?", location).Find(&devices).Error
+	return devices, mapError(err)
+}
+
+func (r *DeviceRepo) Update(ctx context.Context, device *entities.Device) error {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	return mapError(db.Save(device).Error)
 }
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ImageRepo struct {
+	DB *gorm.DB
+}
+
+func NewImageRepo(db *gorm.DB) interfaces.ImageRepository {
+	return &ImageRepo{DB: db}
+}
+
+func (r *ImageRepo) Create(ctx context.Context, image *entities.Image) error {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	image.Timestamp = image.Timestamp.UTC()
+	return mapError(db.Create(image).Error)
+}
+
+func (r *ImageRepo) FindByID(ctx context.Context, id uuid.UUID) (*entities.Image, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var image entities.Image
+	err := db.First(&image, "id = ?", id).Error
+	return &image, mapError(err)
+}
+
+func (r *ImageRepo) FindByDevice(ctx context.Context, deviceID uuid.UUID, start, end time.Time, limit int) ([]entities.Image, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var images []entities.Image
+	query := db.Where(`device_id = ? AND "timestamp" >= ? AND "timestamp" <= ?`, deviceID, start.UTC(), end.UTC()).
+		Order(`"timestamp" desc`)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&images).Error
+	return images, mapError(err)
+}
+
+func (r *ImageRepo) FindMotion(ctx context.Context, deviceID uuid.UUID, start, end time.Time, minScore float64, limit int) ([]entities.Image, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var images []entities.Image
+	query := db.Where(`device_id = ? AND "timestamp" >= ? AND "timestamp" <= ? AND motion_score >= ?`, deviceID, start.UTC(), end.UTC(), minScore).
+		Order(`"timestamp" desc`)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&images).Error
+	return images, mapError(err)
+}
+
+func (r *ImageRepo) Usage(ctx context.Context, deviceID uuid.UUID) (int64, int64, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var usage struct {
+		Frames int64
+		Bytes  int64
+	}
+	err := db.Model(&entities.Image{}).
+		Select("COUNT(*) AS frames, COALESCE(SUM(size_bytes), 0) AS bytes").
+		Where("device_id = ?", deviceID).
+		Scan(&usage).Error
+	return usage.Frames, usage.Bytes, mapError(err)
+}
+
+func (r *ImageRepo) LinkToAlert(ctx context.Context, alertID uuid.UUID, imageIDs []uuid.UUID) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	now := time.Now().UTC()
+	links := make([]entities.AlertEvidence, 0, len(imageIDs))
+	for _, id := range imageIDs {
+		links = append(links, entities.AlertEvidence{AlertID: alertID, ImageID: id, CreatedAt: now})
+	}
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(links, 500).Error
+	return mapError(err)
+}
+
+func (r *ImageRepo) FindByAlert(ctx context.Context, alertID uuid.UUID) ([]entities.Image, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var images []entities.Image
+	err := db.Joins("JOIN alert_evidence ON alert_evidence.image_id = images.id").
+		Where("alert_evidence.alert_id = ?", alertID).
+		Order(`images."timestamp" asc`).Find(&images).Error
+	return images, mapError(err)
+}
+
+func (r *ImageRepo) ScanByDevice(ctx context.Context, deviceID uuid.UUID, from, end time.Time, limit int) ([]entities.Image, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var images []entities.Image
+	err := db.Where(`device_id = ? AND "timestamp" >= ? AND "timestamp" <= ?`, deviceID, from.UTC(), end.UTC()).
+		Order(`"timestamp" asc`).Limit(limit).Find(&images).Error
+	return images, mapError(err)
+}
+
+func (r *ImageRepo) FindExpired(ctx context.Context, deviceID uuid.UUID, cutoff time.Time, limit int) ([]entities.Image, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var images []entities.Image
+	err := db.Where(`device_id = ? AND "timestamp" < ?`, deviceID, cutoff.UTC()).
+		// Links outlive alerts dropped by alert retention, so only links to existing alerts count
+		Where(`NOT EXISTS (SELECT 1 FROM alert_evidence JOIN alerts ON alerts.id = alert_evidence.alert_id
+			WHERE alert_evidence.image_id = images.id)`).
+		Order(`"timestamp" asc`).Limit(limit).Find(&images).Error
+	return images, mapError(err)
+}
+
+func (r *ImageRepo) Delete(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	result := db.Where("id IN ?", ids).Delete(&entities.Image{})
+	return result.RowsAffected, mapError(result.Error)
+}
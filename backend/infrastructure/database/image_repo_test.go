@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"minesense-backend/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+func TestFindExpiredSkipsEvidenceOfExistingAlerts(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+	images := NewImageRepo(db)
+
+	device := &entities.Device{ID: uuid.New(), DeviceName: "cam", Location: "shaft"}
+	if err := NewDeviceRepo(db).Create(ctx, device); err != nil {
+		t.Fatalf("create device: %v", err)
+	}
+	alert := &entities.Alert{ID: uuid.New(), DeviceID: device.ID, AlertType: "Man-Down", Severity: "High", Message: "fall", CreatedAt: time.Now()}
+	if err := NewAlertRepo(db).Create(ctx, alert); err != nil {
+		t.Fatalf("create alert: %v", err)
+	}
+
+	now := time.Now()
+	frame := func(age time.Duration) *entities.Image {
+		image := &entities.Image{ID: uuid.New(), DeviceID: device.ID, StorageKey: uuid.NewString(), ContentType: "image/jpeg", Timestamp: now.Add(-age)}
+		if err := images.Create(ctx, image); err != nil {
+			t.Fatalf("create image: %v", err)
+		}
+		return image
+	}
+	expired := frame(3 * time.Hour)
+	evidence := frame(2 * time.Hour)
+	orphaned := frame(90 * time.Minute) // Linked to an alert that has since been dropped
+	frame(time.Minute)
+
+	if err := images.LinkToAlert(ctx, alert.ID, []uuid.UUID{evidence.ID}); err != nil {
+		t.Fatalf("link evidence: %v", err)
+	}
+	if err := images.LinkToAlert(ctx, uuid.New(), []uuid.UUID{orphaned.ID}); err != nil {
+		t.Fatalf("link evidence: %v", err)
+	}
+
+	found, err := images.FindExpired(ctx, device.ID, now.Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatalf("FindExpired: %v", err)
+	}
+	if len(found) != 2 || found[0].ID != expired.ID || found[1].ID != orphaned.ID {
+		t.Fatalf("FindExpired returned %v, want the expired and orphaned frames", ids(found))
+	}
+}
+
+func ids(images []entities.Image) []uuid.UUID {
+	out := make([]uuid.UUID, len(images))
+	for i := range images {
+		out[i] = images[i].ID
+	}
+	return out
+}
@@ -0,0 +1,290 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Versioned schema migrations. Each change lives in migrations/NNNN_name.up.sql with a matching
+// NNNN_name.down.sql, and applied versions are recorded in schema_migrations.
+// SQLite has its own set under migrations/sqlite since the Postgres schema relies on
+// partitioning, jsonb and gen_random_uuid().
+
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// migrationDirs maps a GORM dialect name to its migration set.
+var migrationDirs = map[string]string{
+	"postgres": "migrations",
+	"sqlite":   "migrations/sqlite",
+}
+
+// migrationLockID is the advisory lock key serialising migrations across instances.
+const migrationLockID = 7342190011
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// LoadMigrations returns the embedded migrations for a dialect ordered by version.
+func LoadMigrations(dialect string) ([]Migration, error) {
+	dir, ok := migrationDirs[dialect]
+	if !ok {
+		return nil, fmt.Errorf("no migrations for database dialect %q", dialect)
+	}
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		file := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(file, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(file, "."+direction+".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name.%s.sql", file, direction)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", file, err)
+		}
+
+		body, err := migrationFiles.ReadFile(path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// lockMigrations serialises migrations across instances. SQLite is single-writer, so only
+// Postgres needs the advisory lock.
+func lockMigrations(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error
+}
+
+// isApplied reports whether the migration is recorded as applied; call it under lockMigrations.
+func isApplied(tx *gorm.DB, version int) (bool, error) {
+	var count int64
+	err := tx.Model(&schemaMigration{}).Where("version = ?", version).Count(&count).Error
+	return count > 0, err
+}
+
+func ensureMigrationsTable(db *gorm.DB) error {
+	// The SQLite driver only decodes times from columns declared DATETIME
+	timeType := "timestamptz"
+	if db.Dialector.Name() == "sqlite" {
+		timeType = "DATETIME"
+	}
+	return db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    bigint PRIMARY KEY,
+    name       text NOT NULL,
+    applied_at ` + timeType + ` NOT NULL
+)`).Error
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]schemaMigration, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// MigrationStatus lists every known migration with the time it was applied, if it was.
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	migrations, err := LoadMigrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Migration: m}
+		if row, ok := applied[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			state.AppliedAt = &appliedAt
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// PendingMigrations returns migrations that have not been applied yet.
+func PendingMigrations(db *gorm.DB) ([]Migration, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, s := range states {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// CheckSchema fails when the database is missing migrations this binary expects.
+func CheckSchema(db *gorm.DB) error {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is behind: %d pending migration(s) starting at %04d_%s; run `server migrate up`",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// MigrateUp applies all pending migrations in order, each in its own transaction, and returns
+// those it ran; migrations another instance applied meanwhile are left out.
+func MigrateUp(db *gorm.DB) ([]Migration, error) {
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range pending {
+		applied := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Serialise with other instances and skip if someone else applied it meanwhile
+			if err := lockMigrations(tx); err != nil {
+				return err
+			}
+			if ok, err := isApplied(tx, m.Version); ok || err != nil {
+				return err
+			}
+			if err := tx.Exec(m.Up).Error; err != nil {
+				return err
+			}
+			applied = true
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if applied {
+			done = append(done, m)
+		}
+	}
+	return done, nil
+}
+
+// MigrateDown reverts the most recently applied migrations, newest first, and returns those it
+// ran.
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(states) - 1; i >= 0 && len(done) < steps; i-- {
+		m := states[i]
+		if m.AppliedAt == nil {
+			continue
+		}
+		if m.Down == "" {
+			return done, fmt.Errorf("migration %04d_%s has no down script", m.Version, m.Name)
+		}
+		reverted := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Likewise skip migrations another instance reverted meanwhile
+			if err := lockMigrations(tx); err != nil {
+				return err
+			}
+			if ok, err := isApplied(tx, m.Version); !ok || err != nil {
+				return err
+			}
+			if err := tx.Exec(m.Down).Error; err != nil {
+				return err
+			}
+			reverted = true
+			return tx.Where("version = ?", m.Version).Delete(&schemaMigration{}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("reverting %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		if reverted {
+			done = append(done, m.Migration)
+		}
+	}
+	return done, nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import "testing"
+
+func TestLoadMigrationsIsOrderedAndReversible(t *testing.T) {
+	for dialect := range migrationDirs {
+		migrations, err := LoadMigrations(dialect)
+		if err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("%s: no migrations", dialect)
+		}
+		for i, m := range migrations {
+			if m.Version != i+1 {
+				t.Fatalf("%s: migration %d has version %d, want %d", dialect, i, m.Version, i+1)
+			}
+			if m.Down == "" {
+				t.Fatalf("%s: %04d_%s has no down script", dialect, m.Version, m.Name)
+			}
+		}
+	}
+	if _, err := LoadMigrations("mysql"); err == nil {
+		t.Fatal("LoadMigrations accepted an unknown dialect")
+	}
+}
+
+func TestMigrateDownRevertsNewestFirst(t *testing.T) {
+	db := openTestSQLite(t)
+	migrations, _ := LoadMigrations("sqlite")
+	tables := func() []string {
+		var names []string
+		if err := db.Raw(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name <> 'schema_migrations' ORDER BY name`).Scan(&names).Error; err != nil {
+			t.Fatalf("list tables: %v", err)
+		}
+		return names
+	}
+	migrated := tables()
+
+	if err := CheckSchema(db); err != nil {
+		t.Fatalf("CheckSchema after MigrateUp: %v", err)
+	}
+	if done, err := MigrateUp(db); err != nil || len(done) != 0 {
+		t.Fatalf("second MigrateUp applied %d migrations (err %v), want none", len(done), err)
+	}
+
+	// One step back leaves the schema behind
+	done, err := MigrateDown(db, 1)
+	if err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if len(done) != 1 || done[0].Version != migrations[len(migrations)-1].Version {
+		t.Fatalf("MigrateDown(1) reverted %v, want the newest migration", done)
+	}
+	if err := CheckSchema(db); err == nil {
+		t.Fatal("CheckSchema passed with a pending migration")
+	}
+
+	// The rest go newest first and leave no tables behind
+	done, err = MigrateDown(db, len(migrations))
+	if err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if len(done) != len(migrations)-1 {
+		t.Fatalf("MigrateDown reverted %d migrations, want %d", len(done), len(migrations)-1)
+	}
+	for i, m := range done {
+		if want := len(migrations) - 1 - i; m.Version != want {
+			t.Fatalf("revert %d was version %d, want %d", i, m.Version, want)
+		}
+	}
+	if left := tables(); len(left) != 0 {
+		t.Fatalf("tables left after reverting every migration: %v", left)
+	}
+
+	// And the schema can be rebuilt from scratch
+	done, err = MigrateUp(db)
+	if err != nil {
+		t.Fatalf("MigrateUp after reverting: %v", err)
+	}
+	if len(done) != len(migrations) {
+		t.Fatalf("MigrateUp applied %d migrations, want %d", len(done), len(migrations))
+	}
+	if got := tables(); len(got) != len(migrated) {
+		t.Fatalf("rebuilt schema has tables %v, want %v", got, migrated)
+	}
+}
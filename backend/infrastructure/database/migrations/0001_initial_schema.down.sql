@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS sensor_readings;
+DROP TABLE IF EXISTS devices;
+DROP TABLE IF EXISTS users;
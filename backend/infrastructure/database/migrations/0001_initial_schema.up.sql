@@ -0,0 +1,41 @@
+-- Baseline schema. Uses IF NOT EXISTS so databases previously created by GORM AutoMigrate adopt it unchanged.
+
+CREATE TABLE IF NOT EXISTS users (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    username   text NOT NULL,
+    password   text NOT NULL,
+    role       text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT uni_users_username UNIQUE (username)
+);
+
+CREATE TABLE IF NOT EXISTS devices (
+    id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_name   text NOT NULL,
+    location      text,
+    supervisor_id uuid,
+    buzzer_active boolean DEFAULT false,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    CONSTRAINT fk_devices_supervisor FOREIGN KEY (supervisor_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS sensor_readings (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_id   uuid NOT NULL,
+    sensor_type text NOT NULL,
+    payload     jsonb,
+    "timestamp" timestamptz,
+    CONSTRAINT fk_sensor_readings_device FOREIGN KEY (device_id) REFERENCES devices (id)
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_id  uuid NOT NULL,
+    alert_type text NOT NULL,
+    severity   text NOT NULL,
+    message    text NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_alerts_device FOREIGN KEY (device_id) REFERENCES devices (id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sensor_rollups_1h;
+DROP TABLE IF EXISTS sensor_rollups_1m;
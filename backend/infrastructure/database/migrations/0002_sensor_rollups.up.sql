@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS sensor_rollups_1m (
+    device_id uuid NOT NULL,
+    bucket    timestamptz NOT NULL,
+    metric    text NOT NULL,
+    min       double precision,
+    max       double precision,
+    sum       double precision,
+    last      double precision,
+    count     bigint,
+    PRIMARY KEY (device_id, bucket, metric)
+);
+
+CREATE TABLE IF NOT EXISTS sensor_rollups_1h (
+    device_id uuid NOT NULL,
+    bucket    timestamptz NOT NULL,
+    metric    text NOT NULL,
+    min       double precision,
+    max       double precision,
+    sum       double precision,
+    last      double precision,
+    count     bigint,
+    PRIMARY KEY (device_id, bucket, metric)
+);
+
+-- Retention deletes and rollup resume points scan by bucket across all devices
+CREATE INDEX IF NOT EXISTS idx_sensor_rollups_1m_bucket ON sensor_rollups_1m (bucket);
+CREATE INDEX IF NOT EXISTS idx_sensor_rollups_1h_bucket ON sensor_rollups_1h (bucket);
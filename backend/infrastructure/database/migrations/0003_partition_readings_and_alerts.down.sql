@@ -0,0 +1,30 @@
+-- Revert to unpartitioned tables, keeping all rows.
+
+ALTER TABLE sensor_readings RENAME TO sensor_readings_partitioned;
+ALTER INDEX sensor_readings_pkey RENAME TO sensor_readings_partitioned_pkey;
+
+CREATE TABLE sensor_readings (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_id   uuid NOT NULL,
+    sensor_type text NOT NULL,
+    payload     jsonb,
+    "timestamp" timestamptz,
+    CONSTRAINT fk_sensor_readings_device FOREIGN KEY (device_id) REFERENCES devices (id)
+);
+INSERT INTO sensor_readings SELECT id, device_id, sensor_type, payload, "timestamp" FROM sensor_readings_partitioned;
+DROP TABLE sensor_readings_partitioned;
+
+ALTER TABLE alerts RENAME TO alerts_partitioned;
+ALTER INDEX alerts_pkey RENAME TO alerts_partitioned_pkey;
+
+CREATE TABLE alerts (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_id  uuid NOT NULL,
+    alert_type text NOT NULL,
+    severity   text NOT NULL,
+    message    text NOT NULL,
+    created_at timestamptz,
+    CONSTRAINT fk_alerts_device FOREIGN KEY (device_id) REFERENCES devices (id)
+);
+INSERT INTO alerts SELECT id, device_id, alert_type, severity, message, created_at FROM alerts_partitioned;
+DROP TABLE alerts_partitioned;
@@ -0,0 +1,79 @@
+-- Convert sensor_readings and alerts into tables range-partitioned by month.
+-- Partitions are named <table>_pYYYYMM and cover [first of month, first of next month) in UTC.
+-- Future partitions are created and old ones dropped by the retention job (PartitionRepo).
+
+-- 1. sensor_readings
+ALTER TABLE sensor_readings RENAME TO sensor_readings_legacy;
+ALTER INDEX sensor_readings_pkey RENAME TO sensor_readings_legacy_pkey;
+
+CREATE TABLE sensor_readings (
+    id          uuid NOT NULL DEFAULT gen_random_uuid(),
+    device_id   uuid NOT NULL,
+    sensor_type text NOT NULL,
+    payload     jsonb,
+    "timestamp" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (id, "timestamp"),
+    CONSTRAINT fk_sensor_readings_device FOREIGN KEY (device_id) REFERENCES devices (id)
+) PARTITION BY RANGE ("timestamp");
+
+CREATE INDEX idx_sensor_readings_device_timestamp ON sensor_readings (device_id, "timestamp" DESC);
+CREATE INDEX idx_sensor_readings_timestamp ON sensor_readings ("timestamp");
+
+-- Catch-all so inserts never fail if partition maintenance falls behind
+CREATE TABLE sensor_readings_default PARTITION OF sensor_readings DEFAULT;
+
+-- 2. alerts
+ALTER TABLE alerts RENAME TO alerts_legacy;
+ALTER INDEX alerts_pkey RENAME TO alerts_legacy_pkey;
+
+CREATE TABLE alerts (
+    id         uuid NOT NULL DEFAULT gen_random_uuid(),
+    device_id  uuid NOT NULL,
+    alert_type text NOT NULL,
+    severity   text NOT NULL,
+    message    text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (id, created_at),
+    CONSTRAINT fk_alerts_device FOREIGN KEY (device_id) REFERENCES devices (id)
+) PARTITION BY RANGE (created_at);
+
+CREATE INDEX idx_alerts_device_created_at ON alerts (device_id, created_at DESC);
+CREATE INDEX idx_alerts_created_at ON alerts (created_at);
+
+CREATE TABLE alerts_default PARTITION OF alerts DEFAULT;
+
+-- 3. Monthly partitions covering existing data through next month, then copy rows across.
+-- Month arithmetic on timestamptz follows the session time zone, so pin it to UTC.
+SET LOCAL TimeZone = 'UTC';
+
+DO $$
+DECLARE
+    tbl   text;
+    col   text;
+    first timestamptz;
+    month timestamptz;
+BEGIN
+    FOR tbl, col IN SELECT * FROM (VALUES ('sensor_readings', 'timestamp'), ('alerts', 'created_at')) AS t (name, partition_column) LOOP
+        EXECUTE format('SELECT min(%I) FROM %I', col, tbl || '_legacy') INTO first;
+        month := date_trunc('month', COALESCE(first, now()) AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+        WHILE month < date_trunc('month', now() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' + interval '2 months' LOOP
+            EXECUTE format(
+                'CREATE TABLE IF NOT EXISTS %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+                tbl || '_p' || to_char(month AT TIME ZONE 'UTC', 'YYYYMM'),
+                tbl,
+                month,
+                month + interval '1 month'
+            );
+            month := month + interval '1 month';
+        END LOOP;
+    END LOOP;
+END $$;
+
+INSERT INTO sensor_readings (id, device_id, sensor_type, payload, "timestamp")
+SELECT id, device_id, sensor_type, payload, COALESCE("timestamp", now()) FROM sensor_readings_legacy;
+
+INSERT INTO alerts (id, device_id, alert_type, severity, message, created_at)
+SELECT id, device_id, alert_type, severity, message, COALESCE(created_at, now()) FROM alerts_legacy;
+
+DROP TABLE sensor_readings_legacy;
+DROP TABLE alerts_legacy;
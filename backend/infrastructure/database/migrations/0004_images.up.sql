@@ -0,0 +1,16 @@
+-- Camera frames. The JPEG lives in the blob store under storage_key; this row is its index.
+
+CREATE TABLE images (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_id    uuid NOT NULL,
+    storage_key  text NOT NULL,
+    content_type text NOT NULL,
+    size_bytes   bigint NOT NULL,
+    width        integer,
+    height       integer,
+    "timestamp"  timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT fk_images_device FOREIGN KEY (device_id) REFERENCES devices (id)
+);
+
+CREATE INDEX idx_images_device_timestamp ON images (device_id, "timestamp" DESC);
+CREATE INDEX idx_images_timestamp ON images ("timestamp");
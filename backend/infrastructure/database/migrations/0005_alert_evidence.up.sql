@@ -0,0 +1,13 @@
+-- Frames linked to an alert as evidence. Alerts are partitioned by created_at and may be
+-- dropped by retention, so alert_id has no foreign key; links go away with their image.
+
+CREATE TABLE alert_evidence (
+    alert_id   uuid NOT NULL,
+    image_id   uuid NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (alert_id, image_id),
+    CONSTRAINT fk_alert_evidence_image FOREIGN KEY (image_id) REFERENCES images (id) ON DELETE CASCADE
+);
+
+-- Image retention skips frames that are evidence
+CREATE INDEX idx_alert_evidence_image ON alert_evidence (image_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS camera_settings;
@@ -0,0 +1,8 @@
+-- Per-camera overrides of frame handling; cameras without a row use the server defaults.
+
+CREATE TABLE camera_settings (
+    device_id       uuid PRIMARY KEY,
+    retention_hours integer CHECK (retention_hours >= 0),
+    updated_at      timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT fk_camera_settings_device FOREIGN KEY (device_id) REFERENCES devices (id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+ALTER TABLE camera_settings
+    DROP COLUMN IF EXISTS motion_threshold,
+    DROP COLUMN IF EXISTS restricted_area;
+
+ALTER TABLE images
+    DROP COLUMN IF EXISTS brightness,
+    DROP COLUMN IF EXISTS motion_score;
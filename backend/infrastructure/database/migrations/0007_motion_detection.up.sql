@@ -0,0 +1,9 @@
+-- Motion detection: per-frame scene change and brightness, and per-camera alert settings.
+
+ALTER TABLE images
+    ADD COLUMN motion_score real,
+    ADD COLUMN brightness   real;
+
+ALTER TABLE camera_settings
+    ADD COLUMN restricted_area  boolean NOT NULL DEFAULT false,
+    ADD COLUMN motion_threshold real CHECK (motion_threshold > 0 AND motion_threshold <= 1);
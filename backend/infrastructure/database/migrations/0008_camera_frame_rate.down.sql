@@ -0,0 +1,2 @@
+ALTER TABLE camera_settings
+    DROP COLUMN IF EXISTS target_fps;
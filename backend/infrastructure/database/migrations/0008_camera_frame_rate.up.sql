@@ -0,0 +1,4 @@
+-- Per-camera target frame rate, sent to the camera and enforced on upload.
+
+ALTER TABLE camera_settings
+    ADD COLUMN target_fps real CHECK (target_fps > 0 AND target_fps <= 30);
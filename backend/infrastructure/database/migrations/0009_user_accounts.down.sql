@@ -0,0 +1,4 @@
+-- Roles mapped by the up migration are not restored.
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS disabled;
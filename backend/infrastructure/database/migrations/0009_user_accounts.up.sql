@@ -0,0 +1,13 @@
+-- Admin-managed accounts: disabled users can no longer log in or use their tokens.
+
+ALTER TABLE users
+    ADD COLUMN disabled boolean NOT NULL DEFAULT false;
+
+-- Roles are now limited to Admin, Supervisor and Worker. Map differently cased spellings onto
+-- them and anything else (such as the old default "User") to the least privileged role.
+UPDATE users SET role = CASE lower(role)
+        WHEN 'admin' THEN 'Admin'
+        WHEN 'supervisor' THEN 'Supervisor'
+        ELSE 'Worker'
+    END
+WHERE role NOT IN ('Admin', 'Supervisor', 'Worker');
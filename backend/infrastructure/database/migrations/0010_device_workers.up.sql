@@ -0,0 +1,6 @@
+-- The Worker wearing or carrying a device, who may follow it on the event stream.
+
+ALTER TABLE devices
+    ADD COLUMN worker_id uuid;
+ALTER TABLE devices
+    ADD CONSTRAINT fk_devices_worker FOREIGN KEY (worker_id) REFERENCES users (id);
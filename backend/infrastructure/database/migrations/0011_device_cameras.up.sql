@@ -0,0 +1,7 @@
+-- Whether a device may upload camera frames. Devices that already sent frames or have camera
+-- settings are cameras.
+
+ALTER TABLE devices
+    ADD COLUMN has_camera boolean NOT NULL DEFAULT false;
+UPDATE devices SET has_camera = true
+WHERE id IN (SELECT device_id FROM images UNION SELECT device_id FROM camera_settings);
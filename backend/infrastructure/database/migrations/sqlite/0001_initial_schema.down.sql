@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS sensor_rollups_1h;
+DROP TABLE IF EXISTS sensor_rollups_1m;
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS sensor_readings;
+DROP TABLE IF EXISTS devices;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,72 @@
+-- SQLite schema for single-site deployments. Equivalent to the Postgres migrations 0001-0003
+-- without partitioning: UUIDs are stored as text (random v4 by default), payloads as JSON text
+-- and times as DATETIME text in UTC so they sort chronologically.
+
+CREATE TABLE users (
+    id         text PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    username   text NOT NULL UNIQUE,
+    password   text NOT NULL,
+    role       text NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+
+CREATE TABLE devices (
+    id            text PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    device_name   text NOT NULL,
+    location      text,
+    supervisor_id text REFERENCES users (id),
+    buzzer_active boolean DEFAULT false,
+    created_at    DATETIME,
+    updated_at    DATETIME
+);
+
+CREATE TABLE sensor_readings (
+    id          text PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    device_id   text NOT NULL REFERENCES devices (id),
+    sensor_type text NOT NULL,
+    payload     text CHECK (payload IS NULL OR json_valid(payload)),
+    "timestamp" DATETIME
+);
+
+CREATE INDEX idx_sensor_readings_device_timestamp ON sensor_readings (device_id, "timestamp" DESC);
+CREATE INDEX idx_sensor_readings_timestamp ON sensor_readings ("timestamp");
+
+CREATE TABLE alerts (
+    id         text PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    device_id  text NOT NULL REFERENCES devices (id),
+    alert_type text NOT NULL,
+    severity   text NOT NULL,
+    message    text NOT NULL,
+    created_at DATETIME
+);
+
+CREATE INDEX idx_alerts_device_created_at ON alerts (device_id, created_at DESC);
+CREATE INDEX idx_alerts_created_at ON alerts (created_at);
+
+CREATE TABLE sensor_rollups_1m (
+    device_id text NOT NULL,
+    bucket    DATETIME NOT NULL,
+    metric    text NOT NULL,
+    min       real,
+    max       real,
+    sum       real,
+    last      real,
+    count     integer,
+    PRIMARY KEY (device_id, bucket, metric)
+);
+
+CREATE TABLE sensor_rollups_1h (
+    device_id text NOT NULL,
+    bucket    DATETIME NOT NULL,
+    metric    text NOT NULL,
+    min       real,
+    max       real,
+    sum       real,
+    last      real,
+    count     integer,
+    PRIMARY KEY (device_id, bucket, metric)
+);
+
+CREATE INDEX idx_sensor_rollups_1m_bucket ON sensor_rollups_1m (bucket);
+CREATE INDEX idx_sensor_rollups_1h_bucket ON sensor_rollups_1h (bucket);
@@ -0,0 +1,15 @@
+-- Camera frames; equivalent to the Postgres migration 0004.
+
+CREATE TABLE images (
+    id           text PRIMARY KEY DEFAULT (lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))),
+    device_id    text NOT NULL REFERENCES devices (id),
+    storage_key  text NOT NULL,
+    content_type text NOT NULL,
+    size_bytes   integer NOT NULL,
+    width        integer,
+    height       integer,
+    "timestamp"  DATETIME NOT NULL
+);
+
+CREATE INDEX idx_images_device_timestamp ON images (device_id, "timestamp" DESC);
+CREATE INDEX idx_images_timestamp ON images ("timestamp");
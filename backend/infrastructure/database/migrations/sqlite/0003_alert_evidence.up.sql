@@ -0,0 +1,10 @@
+-- Frames linked to an alert as evidence; equivalent to the Postgres migration 0005.
+
+CREATE TABLE alert_evidence (
+    alert_id   text NOT NULL,
+    image_id   text NOT NULL REFERENCES images (id) ON DELETE CASCADE,
+    created_at DATETIME,
+    PRIMARY KEY (alert_id, image_id)
+);
+
+CREATE INDEX idx_alert_evidence_image ON alert_evidence (image_id);
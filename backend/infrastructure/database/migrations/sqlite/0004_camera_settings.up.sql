@@ -0,0 +1,7 @@
+-- Per-camera overrides of frame handling; equivalent to the Postgres migration 0006.
+
+CREATE TABLE camera_settings (
+    device_id       text PRIMARY KEY REFERENCES devices (id) ON DELETE CASCADE,
+    retention_hours integer CHECK (retention_hours >= 0),
+    updated_at      DATETIME
+);
@@ -0,0 +1,5 @@
+ALTER TABLE camera_settings DROP COLUMN motion_threshold;
+ALTER TABLE camera_settings DROP COLUMN restricted_area;
+
+ALTER TABLE images DROP COLUMN brightness;
+ALTER TABLE images DROP COLUMN motion_score;
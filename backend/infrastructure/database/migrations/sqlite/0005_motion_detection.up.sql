@@ -0,0 +1,7 @@
+-- Motion detection; equivalent to the Postgres migration 0007.
+
+ALTER TABLE images ADD COLUMN motion_score real;
+ALTER TABLE images ADD COLUMN brightness real;
+
+ALTER TABLE camera_settings ADD COLUMN restricted_area boolean NOT NULL DEFAULT false;
+ALTER TABLE camera_settings ADD COLUMN motion_threshold real CHECK (motion_threshold > 0 AND motion_threshold <= 1);
@@ -0,0 +1 @@
+ALTER TABLE camera_settings DROP COLUMN target_fps;
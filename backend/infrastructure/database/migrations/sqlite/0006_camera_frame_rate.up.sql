@@ -0,0 +1,3 @@
+-- Per-camera target frame rate; equivalent to the Postgres migration 0008.
+
+ALTER TABLE camera_settings ADD COLUMN target_fps real CHECK (target_fps > 0 AND target_fps <= 30);
@@ -0,0 +1,10 @@
+-- Admin-managed accounts; equivalent to the Postgres migration 0009.
+
+ALTER TABLE users ADD COLUMN disabled boolean NOT NULL DEFAULT false;
+
+UPDATE users SET role = CASE lower(role)
+        WHEN 'admin' THEN 'Admin'
+        WHEN 'supervisor' THEN 'Supervisor'
+        ELSE 'Worker'
+    END
+WHERE role NOT IN ('Admin', 'Supervisor', 'Worker');
@@ -0,0 +1,3 @@
+-- The Worker wearing or carrying a device; equivalent to the Postgres migration 0010.
+
+ALTER TABLE devices ADD COLUMN worker_id text REFERENCES users (id);
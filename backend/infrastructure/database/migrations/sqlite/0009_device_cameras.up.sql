@@ -0,0 +1,5 @@
+-- Whether a device may upload camera frames; equivalent to the Postgres migration 0011.
+
+ALTER TABLE devices ADD COLUMN has_camera boolean NOT NULL DEFAULT false;
+UPDATE devices SET has_camera = true
+WHERE id IN (SELECT device_id FROM images UNION SELECT device_id FROM camera_settings);
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// maxNotifyPayload is Postgres' limit on a NOTIFY payload, in bytes.
+const maxNotifyPayload = 8000
+
+// NotifyBackend relays WebSocket hub messages between backend instances over Postgres LISTEN/NOTIFY.
+// Messages are published through the shared pool; listening needs a dedicated session connection,
+// which a transaction pooler (e.g. Supabase on port 6543) cannot provide, so it may use its own DSN.
+type NotifyBackend struct {
+	DB        *gorm.DB
+	ListenDSN string
+	Channel   string
+	// origin identifies this instance so it skips its own notifications, which it already delivered.
+	origin string
+}
+
+type notifyEnvelope struct {
+	Origin string          `json:"o"`
+	Body   json.RawMessage `json:"b"`
+}
+
+func NewNotifyBackend(db *gorm.DB, listenDSN, channel string) *NotifyBackend {
+	return &NotifyBackend{DB: db, ListenDSN: listenDSN, Channel: channel, origin: uuid.NewString()}
+}
+
+func (b *NotifyBackend) Publish(ctx context.Context, body []byte) error {
+	payload, err := json.Marshal(notifyEnvelope{Origin: b.origin, Body: body})
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		return fmt.Errorf("message of %d bytes exceeds the %d byte NOTIFY limit", len(payload), maxNotifyPayload)
+	}
+	db, cancel := session(b.DB, ctx)
+	defer cancel()
+	return mapError(db.Exec("SELECT pg_notify(?, ?)", b.Channel, string(payload)).Error)
+}
+
+func (b *NotifyBackend) Listen(ctx context.Context, deliver func(body []byte)) error {
+	conn, err := pgx.Connect(ctx, b.ListenDSN)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.Channel}.Sanitize()); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var envelope notifyEnvelope
+		if err := json.Unmarshal([]byte(n.Payload), &envelope); err != nil || envelope.Origin == b.origin {
+			continue
+		}
+		deliver(envelope.Body)
+	}
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"minesense-backend/domain/interfaces"
+
+	"gorm.io/gorm"
+)
+
+// PartitionRepo maintains the monthly range partitions of sensor_readings and alerts.
+// Partitions follow the <table>_pYYYYMM naming convention set up by migration 0003.
+type PartitionRepo struct {
+	DB *gorm.DB
+}
+
+func NewPartitionRepo(db *gorm.DB) interfaces.PartitionRepository {
+	return &PartitionRepo{DB: db}
+}
+
+// partitionedTables maps each partitioned table to its partition column, and guards against
+// building DDL from arbitrary table names.
+var partitionedTables = map[string]string{
+	"sensor_readings": `"timestamp"`,
+	"alerts":          "created_at",
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func partitionName(table string, month time.Time) string {
+	return fmt.Sprintf("%s_p%s", table, month.Format("200601"))
+}
+
+// EnsureMonthlyPartitions creates partitions for the month containing `from` and the following monthsAhead months.
+// Rows that landed in the default partition because their month had no partition yet are moved
+// into the new partition, which Postgres would otherwise refuse to create.
+func (r *PartitionRepo) EnsureMonthlyPartitions(ctx context.Context, table string, from time.Time, monthsAhead int) error {
+	column, ok := partitionedTables[table]
+	if !ok {
+		return fmt.Errorf("table %q is not partitioned", table)
+	}
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+
+	month := monthStart(from)
+	for i := 0; i <= monthsAhead; i++ {
+		next := month.AddDate(0, 1, 0)
+		if err := createPartition(db, table, column, month, next); err != nil {
+			return fmt.Errorf("create partition %s: %w", partitionName(table, month), mapError(err))
+		}
+		month = next
+	}
+	return nil
+}
+
+func createPartition(db *gorm.DB, table, column string, month, next time.Time) error {
+	name := partitionName(table, month)
+	var exists bool
+	if err := db.Raw(`SELECT to_regclass(?) IS NOT NULL`, name).Scan(&exists).Error; err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	ddl := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+		name, table, month.Format(time.RFC3339), next.Format(time.RFC3339))
+
+	defaultPartition := table + "_default"
+	inMonth := fmt.Sprintf(`%s >= ? AND %s < ?`, column, column)
+	var stranded bool
+	err := db.Raw(fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE %s)`, defaultPartition, inMonth), month, next).Scan(&stranded).Error
+	if err != nil {
+		return err
+	}
+	if !stranded {
+		return db.Exec(ddl).Error
+	}
+
+	// Detach the default partition, create the month's partition and move the rows across.
+	// Inserts into the table wait for the transaction.
+	return db.Transaction(func(tx *gorm.DB) error {
+		steps := []struct {
+			sql  string
+			args []interface{}
+		}{
+			{sql: fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, table, defaultPartition)},
+			{sql: ddl},
+			{sql: fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s WHERE %s`, table, defaultPartition, inMonth), args: []interface{}{month, next}},
+			{sql: fmt.Sprintf(`DELETE FROM %s WHERE %s`, defaultPartition, inMonth), args: []interface{}{month, next}},
+			{sql: fmt.Sprintf(`ALTER TABLE %s ATTACH PARTITION %s DEFAULT`, table, defaultPartition)},
+		}
+		for _, step := range steps {
+			if err := tx.Exec(step.sql, step.args...).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DropPartitionsBefore drops every monthly partition whose whole range ends at or before cutoff.
+func (r *PartitionRepo) DropPartitionsBefore(ctx context.Context, table string, cutoff time.Time) ([]string, error) {
+	if _, ok := partitionedTables[table]; !ok {
+		return nil, fmt.Errorf("table %q is not partitioned", table)
+	}
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+
+	var children []string
+	err := db.Raw(`
+SELECT c.relname
+FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+JOIN pg_class p ON p.oid = i.inhparent
+WHERE p.relname = ?
+ORDER BY c.relname`, table).Scan(&children).Error
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	prefix := table + "_p"
+	var dropped []string
+	for _, name := range children {
+		suffix, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue // e.g. the default partition
+		}
+		month, err := time.Parse("200601", suffix)
+		if err != nil {
+			continue
+		}
+		if month.AddDate(0, 1, 0).After(cutoff) {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, name)).Error; err != nil {
+			return dropped, fmt.Errorf("drop partition %s: %w", name, mapError(err))
+		}
+		dropped = append(dropped, name)
+	}
+	return dropped, nil
+}
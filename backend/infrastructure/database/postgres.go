@@ -5,7 +5,6 @@ import (
 	"log"
 
 	"minesense-backend/config"
-	"minesense-backend/domain/entities"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -15,24 +14,8 @@ var DB *gorm.DB
 
 func ConnectDB(cfg *config.Config) {
 	var err error
-	var dsn string
-
-	// Check if a full DATABASE_URL is provided (common in cloud platforms like Render/Supabase)
-	if cfg.DatabaseURL != "" {
-		dsn = cfg.DatabaseURL
-	} else {
-		// Fallback to individual components for local development
-		dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
-			cfg.DBHost,
-			cfg.DBUser,
-			cfg.DBPassword,
-			cfg.DBName,
-			cfg.DBPort,
-		)
-	}
-
 	DB, err = gorm.Open(postgres.New(postgres.Config{
-		DSN:                  dsn,
+		DSN:                  PostgresDSN(cfg),
 		PreferSimpleProtocol: true, // Required for Supabase Transaction Pooler (Port 6543)
 	}), &gorm.Config{
 		PrepareStmt: false, // Disable GORM's prepared statement caching
@@ -40,19 +23,23 @@ func ConnectDB(cfg *config.Config) {
 	if err != nil {
 		log.Fatal("Failed to connect to database: ", err)
 	}
+	QueryTimeout = cfg.DBQueryTimeout
 
 	log.Println("Connected to database successfully")
+}
 
-	// Auto Migrate
-	err = DB.AutoMigrate(
-		&entities.User{},
-		&entities.Device{},
-		&entities.SensorReading{},
-		&entities.Alert{},
-	)
-	if err != nil {
-		log.Printf("Warning: Database migration encountered an issue: %v. Continuing application startup...", err)
-	} else {
-		log.Println("Database migration completed")
+// PostgresDSN returns the connection string for the configured Postgres database.
+func PostgresDSN(cfg *config.Config) string {
+	// Check if a full DATABASE_URL is provided (common in cloud platforms like Render/Supabase)
+	if cfg.DatabaseURL != "" {
+		return cfg.DatabaseURL
 	}
+	// Fallback to individual components for local development
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
+		cfg.DBHost,
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBName,
+		cfg.DBPort,
+	)
 }
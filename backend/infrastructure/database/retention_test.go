@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/usecases"
+
+	"github.com/google/uuid"
+)
+
+func TestRetentionExpiresAlertsWithoutPartitions(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+	alerts := NewAlertRepo(db)
+
+	device := &entities.Device{ID: uuid.New(), DeviceName: "helmet"}
+	if err := NewDeviceRepo(db).Create(ctx, device); err != nil {
+		t.Fatalf("create device: %v", err)
+	}
+	now := time.Now()
+	for _, age := range []time.Duration{90 * 24 * time.Hour, 45 * 24 * time.Hour, 31 * 24 * time.Hour, time.Hour} {
+		alert := &entities.Alert{DeviceID: device.ID, AlertType: "Gas Hazard", Severity: "High", Message: "gas", CreatedAt: now.Add(-age)}
+		if err := alerts.Create(ctx, alert); err != nil {
+			t.Fatalf("create alert: %v", err)
+		}
+	}
+
+	// A batch size of 2 makes the delete take several batches
+	retention := usecases.NewRetentionUseCase(NewSQLiteRollupRepo(db), NewSQLitePartitionRepo(), alerts, usecases.RetentionPolicy{
+		AlertRetention: 30 * 24 * time.Hour,
+		BatchSize:      2,
+	})
+	retention.RunOnce(ctx)
+
+	left, err := alerts.FindAll(ctx)
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if len(left) != 1 || left[0].CreatedAt.Before(now.Add(-2*time.Hour)) {
+		t.Fatalf("%d alerts left after retention, want only the recent one", len(left))
+	}
+}
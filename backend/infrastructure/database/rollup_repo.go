@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"gorm.io/gorm"
+)
+
+type RollupRepo struct {
+	DB *gorm.DB
+}
+
+func NewRollupRepo(db *gorm.DB) interfaces.SensorRollupRepository {
+	return &RollupRepo{DB: db}
+}
+
+const rollupUpsertSQL = `
+ON CONFLICT (device_id, bucket, metric) DO UPDATE SET
+    min = EXCLUDED.min,
+    max = EXCLUDED.max,
+    sum = EXCLUDED.sum,
+    last = EXCLUDED.last,
+    count = EXCLUDED.count`
+
+func (r *RollupRepo) RollupRaw(ctx context.Context, from, to time.Time) (int64, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	query := `
+INSERT INTO ` + entities.RollupTable(entities.RollupMinute) + ` (device_id, bucket, metric, min, max, sum, last, count)
+SELECT r.device_id,
+       date_trunc('minute', r."timestamp"),
+       kv.key,
+       min(kv.num),
+       max(kv.num),
+       sum(kv.num),
+       (array_agg(kv.num ORDER BY r."timestamp" DESC))[1],
+       count(*)
+FROM sensor_readings r
+CROSS JOIN LATERAL (` + payloadMetricsSQL + `) kv
+WHERE r."timestamp" >= @from AND r."timestamp" < @to
+  AND kv.num IS NOT NULL
+GROUP BY 1, 2, 3` + rollupUpsertSQL
+
+	result := db.Exec(query, map[string]interface{}{"from": from, "to": to})
+	return result.RowsAffected, mapError(result.Error)
+}
+
+func (r *RollupRepo) RollupMinutes(ctx context.Context, from, to time.Time) (int64, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	query := `
+INSERT INTO ` + entities.RollupTable(entities.RollupHour) + ` (device_id, bucket, metric, min, max, sum, last, count)
+SELECT device_id,
+       date_trunc('hour', bucket),
+       metric,
+       min(min),
+       max(max),
+       sum(sum),
+       (array_agg(last ORDER BY bucket DESC))[1],
+       sum(count)
+FROM ` + entities.RollupTable(entities.RollupMinute) + `
+WHERE bucket >= @from AND bucket < @to
+GROUP BY 1, 2, 3` + rollupUpsertSQL
+
+	result := db.Exec(query, map[string]interface{}{"from": from, "to": to})
+	return result.RowsAffected, mapError(result.Error)
+}
+
+func (r *RollupRepo) Watermark(ctx context.Context, resolution string) (time.Time, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var latest sql.NullTime
+	err := db.Table(entities.RollupTable(resolution)).Select("max(bucket)").Row().Scan(&latest)
+	return latest.Time, mapError(err)
+}
+
+func (r *RollupRepo) OldestRawTimestamp(ctx context.Context, after time.Time) (time.Time, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var oldest sql.NullTime
+	err := db.Table("sensor_readings").Select(`min("timestamp")`).Where(`"timestamp" >= ?`, after).Row().Scan(&oldest)
+	return oldest.Time, mapError(err)
+}
+
+func (r *RollupRepo) OldestRollup(ctx context.Context, resolution string, after time.Time) (time.Time, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var oldest sql.NullTime
+	err := db.Table(entities.RollupTable(resolution)).Select("min(bucket)").Where("bucket >= ?", after).Row().Scan(&oldest)
+	return oldest.Time, mapError(err)
+}
+
+// DeleteRawBefore removes raw readings older than cutoff in batches so no single
+// statement holds long locks on the hot table.
+func (r *RollupRepo) DeleteRawBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	return r.deleteInBatches(ctx, `
+DELETE FROM sensor_readings
+WHERE id IN (SELECT id FROM sensor_readings WHERE "timestamp" < @cutoff LIMIT @batch)`, cutoff, batchSize)
+}
+
+func (r *RollupRepo) DeleteRollupsBefore(ctx context.Context, resolution string, cutoff time.Time, batchSize int) (int64, error) {
+	table := entities.RollupTable(resolution)
+	return r.deleteInBatches(ctx, `
+DELETE FROM `+table+`
+WHERE ctid IN (SELECT ctid FROM `+table+` WHERE bucket < @cutoff LIMIT @batch)`, cutoff, batchSize)
+}
+
+// deleteInBatches repeats a batched DELETE until it removes fewer than batchSize rows.
+// The query timeout applies to each batch rather than the whole purge.
+func (r *RollupRepo) deleteInBatches(ctx context.Context, query string, cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		db, cancel := session(r.DB, ctx)
+		result := db.Exec(query, map[string]interface{}{"cutoff": cutoff, "batch": batchSize})
+		cancel()
+		if result.Error != nil {
+			return total, mapError(result.Error)
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
+
+// Aggregate re-buckets rollups of the given resolution into the query's bucket width.
+func (r *RollupRepo) Aggregate(ctx context.Context, resolution string, q entities.SensorAggregateQuery) ([]entities.SensorAggregate, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	bucketSeconds := int64(q.Bucket.Seconds())
+	if bucketSeconds < 1 {
+		bucketSeconds = 1
+	}
+
+	query := `
+SELECT device_id,
+       to_timestamp(floor(extract(epoch FROM bucket) / @bucket) * @bucket) AS bucket,
+       metric,
+       min(min) AS min,
+       max(max) AS max,
+       sum(sum) / NULLIF(sum(count), 0) AS avg,
+       (array_agg(last ORDER BY bucket DESC))[1] AS last,
+       sum(count) AS count
+FROM ` + entities.RollupTable(resolution) + `
+WHERE device_id IN @devices`
+	args := map[string]interface{}{
+		"bucket":  bucketSeconds,
+		"devices": q.DeviceIDs,
+	}
+	if !q.Start.IsZero() {
+		query += ` AND bucket >= @start`
+		args["start"] = q.Start
+	}
+	if !q.End.IsZero() {
+		query += ` AND bucket <= @end`
+		args["end"] = q.End
+	}
+	if len(q.Metrics) > 0 {
+		query += ` AND metric IN @metrics`
+		args["metrics"] = q.Metrics
+	}
+	query += `
+GROUP BY 1, 2, 3
+ORDER BY 2 ASC, 1, 3`
+
+	var aggregates []entities.SensorAggregate
+	err := db.Raw(query, args).Scan(&aggregates).Error
+	return aggregates, mapError(err)
+}
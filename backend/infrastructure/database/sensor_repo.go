@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"minesense-backend/domain/entities"
 	"minesense-backend/domain/interfaces"
 
@@ -16,25 +17,33 @@ func NewSensorRepo(db *gorm.DB) interfaces.SensorRepository {
 	return &SensorRepo{DB: db}
 }
 
-func (r *SensorRepo) Create(reading *entities.SensorReading) error {
-	return r.DB.Create(reading).Error
+func (r *SensorRepo) Create(ctx context.Context, reading *entities.SensorReading) error {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	return mapError(db.Create(reading).Error)
 }
 
-func (r *SensorRepo) FindByDeviceID(deviceID uuid.UUID) ([]entities.SensorReading, error) {
+func (r *SensorRepo) FindByDeviceID(ctx context.Context, deviceID uuid.UUID) ([]entities.SensorReading, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
 	var readings []entities.SensorReading
-	err := r.DB.Where("device_id = ?", deviceID).Find(&readings).Error
-	return readings, err
+	err := db.Where("device_id = ?", deviceID).Find(&readings).Error
+	return readings, mapError(err)
 }
 
-func (r *SensorRepo) GetLatestByDeviceID(deviceID uuid.UUID) (*entities.SensorReading, error) {
+func (r *SensorRepo) GetLatestByDeviceID(ctx context.Context, deviceID uuid.UUID) (*entities.SensorReading, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
 	var reading entities.SensorReading
-	err := r.DB.Where("device_id = ?", deviceID).Order("timestamp desc").First(&reading).Error
-	return &reading, err
+	err := db.Where("device_id = ?", deviceID).Order("timestamp desc").First(&reading).Error
+	return &reading, mapError(err)
 }
 
-func (r *SensorRepo) GetHistory(deviceID uuid.UUID, start, end string) ([]entities.SensorReading, error) {
+func (r *SensorRepo) GetHistory(ctx context.Context, deviceID uuid.UUID, start, end string) ([]entities.SensorReading, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
 	var readings []entities.SensorReading
-	query := r.DB.Where("device_id = ?", deviceID)
+	query := db.Where("device_id = ?", deviceID)
 	if start != "" {
 		query = query.Where("timestamp >= ?", start)
 	}
@@ -42,5 +51,64 @@ func (r *SensorRepo) GetHistory(deviceID uuid.UUID, start, end string) ([]entiti
 		query = query.Where("timestamp <= ?", end)
 	}
 	err := query.Order("timestamp asc").Find(&readings).Error
-	return readings, err
+	return readings, mapError(err)
+}
+
+// payloadMetricsSQL expands a reading's JSONB payload into (key, num) rows, keeping numeric
+// values and booleans (as 0/1). Used as CROSS JOIN LATERAL (...) kv against sensor_readings r.
+const payloadMetricsSQL = `
+    SELECT e.key,
+           CASE jsonb_typeof(e.value)
+               WHEN 'number' THEN (e.value #>> '{}')::float8
+               WHEN 'boolean' THEN CASE WHEN (e.value #>> '{}')::boolean THEN 1 ELSE 0 END
+           END AS num
+    FROM jsonb_each(CASE WHEN jsonb_typeof(r.payload) = 'object' THEN r.payload ELSE '{}'::jsonb END) e
+`
+
+// Aggregate buckets readings by time and computes min/max/avg/last/count for every
+// numeric (or boolean, as 0/1) key in the JSONB payload, entirely in Postgres.
+func (r *SensorRepo) Aggregate(ctx context.Context, q entities.SensorAggregateQuery) ([]entities.SensorAggregate, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	bucketSeconds := int64(q.Bucket.Seconds())
+	if bucketSeconds < 1 {
+		bucketSeconds = 1
+	}
+
+	sql := `
+SELECT r.device_id,
+       to_timestamp(floor(extract(epoch FROM r."timestamp") / @bucket) * @bucket) AS bucket,
+       kv.key AS metric,
+       min(kv.num) AS min,
+       max(kv.num) AS max,
+       avg(kv.num) AS avg,
+       (array_agg(kv.num ORDER BY r."timestamp" DESC))[1] AS last,
+       count(*) AS count
+FROM sensor_readings r
+CROSS JOIN LATERAL (` + payloadMetricsSQL + `) kv
+WHERE r.device_id IN @devices
+  AND kv.num IS NOT NULL`
+	args := map[string]interface{}{
+		"bucket":  bucketSeconds,
+		"devices": q.DeviceIDs,
+	}
+	if !q.Start.IsZero() {
+		sql += ` AND r."timestamp" >= @start`
+		args["start"] = q.Start
+	}
+	if !q.End.IsZero() {
+		sql += ` AND r."timestamp" <= @end`
+		args["end"] = q.End
+	}
+	if len(q.Metrics) > 0 {
+		sql += ` AND kv.key IN @metrics`
+		args["metrics"] = q.Metrics
+	}
+	sql += `
+GROUP BY 1, 2, 3
+ORDER BY 2 ASC, 1, 3`
+
+	var aggregates []entities.SensorAggregate
+	err := db.Raw(sql, args).Scan(&aggregates).Error
+	return aggregates, mapError(err)
 }
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"log"
+	"reflect"
+	"time"
+
+	"minesense-backend/config"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// ConnectSQLite opens the embedded SQLite database used by single-site deployments.
+func ConnectSQLite(cfg *config.Config) {
+	var err error
+
+	// WAL lets readers proceed during writes; busy_timeout waits for the write lock instead of failing
+	dsn := cfg.SQLitePath + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=synchronous(NORMAL)"
+	DB, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		log.Fatal("Failed to open SQLite database: ", err)
+	}
+	QueryTimeout = cfg.DBQueryTimeout
+
+	// SQLite allows a single writer; one connection avoids lock upgrade failures between our own goroutines
+	sqlDB, err := DB.DB()
+	if err != nil {
+		log.Fatal("Failed to open SQLite database: ", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := registerUTCTimes(DB); err != nil {
+		log.Fatal("Failed to configure SQLite database: ", err)
+	}
+
+	log.Printf("Opened SQLite database %s", cfg.SQLitePath)
+}
+
+// registerUTCTimes converts every time field to UTC before it is written. SQLite stores times
+// as text, so mixing offsets would break chronological ordering and range filters.
+func registerUTCTimes(db *gorm.DB) error {
+	toUTC := func(tx *gorm.DB) {
+		if tx.Statement.Schema == nil {
+			return
+		}
+		rv := tx.Statement.ReflectValue
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				utcFields(tx, reflect.Indirect(rv.Index(i)))
+			}
+		case reflect.Struct:
+			utcFields(tx, rv)
+		}
+	}
+	if err := db.Callback().Create().Before("gorm:create").Register("minesense:utc_times", toUTC); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("minesense:utc_times", toUTC)
+}
+
+func utcFields(tx *gorm.DB, rv reflect.Value) {
+	for _, field := range tx.Statement.Schema.Fields {
+		value, zero := field.ValueOf(tx.Statement.Context, rv)
+		if zero {
+			continue
+		}
+		if t, ok := value.(time.Time); ok && t.Location() != time.UTC {
+			_ = field.Set(tx.Statement.Context, rv, t.UTC())
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"gorm.io/gorm"
+)
+
+// SQLiteRollupRepo maintains the rollup tables in SQLite. Buckets are written in the same
+// UTC text format the driver uses for bound times so range comparisons stay chronological.
+type SQLiteRollupRepo struct {
+	DB *gorm.DB
+}
+
+func NewSQLiteRollupRepo(db *gorm.DB) interfaces.SensorRollupRepository {
+	return &SQLiteRollupRepo{DB: db}
+}
+
+func (r *SQLiteRollupRepo) RollupRaw(ctx context.Context, from, to time.Time) (int64, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	query := `
+INSERT INTO ` + entities.RollupTable(entities.RollupMinute) + ` (device_id, bucket, metric, min, max, sum, last, count)
+SELECT device_id, bucket, metric, min(num), max(num), sum(num), max(last), count(*)
+FROM (
+    SELECT m.*, first_value(num) OVER (PARTITION BY device_id, bucket, metric ORDER BY ts DESC) AS last
+    FROM (
+        SELECT r.device_id,
+               strftime('%Y-%m-%d %H:%M:00+00:00', r."timestamp") AS bucket,
+               kv.key AS metric,
+               ` + sqlitePayloadNumSQL + ` AS num,
+               r."timestamp" AS ts
+        FROM sensor_readings r, ` + sqlitePayloadMetricsSQL + `
+        WHERE r."timestamp" >= @from AND r."timestamp" < @to
+    ) m
+    WHERE num IS NOT NULL
+)
+GROUP BY 1, 2, 3` + rollupUpsertSQL
+
+	result := db.Exec(query, map[string]interface{}{"from": from.UTC(), "to": to.UTC()})
+	return result.RowsAffected, mapError(result.Error)
+}
+
+func (r *SQLiteRollupRepo) RollupMinutes(ctx context.Context, from, to time.Time) (int64, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	query := `
+INSERT INTO ` + entities.RollupTable(entities.RollupHour) + ` (device_id, bucket, metric, min, max, sum, last, count)
+SELECT device_id, hour, metric, min(min), max(max), sum(sum), max(hour_last), sum(count)
+FROM (
+    SELECT device_id,
+           strftime('%Y-%m-%d %H:00:00+00:00', bucket) AS hour,
+           metric, min, max, sum, count,
+           first_value(last) OVER (PARTITION BY device_id, strftime('%Y-%m-%d %H', bucket), metric ORDER BY bucket DESC) AS hour_last
+    FROM ` + entities.RollupTable(entities.RollupMinute) + `
+    WHERE bucket >= @from AND bucket < @to
+)
+GROUP BY 1, 2, 3` + rollupUpsertSQL
+
+	result := db.Exec(query, map[string]interface{}{"from": from.UTC(), "to": to.UTC()})
+	return result.RowsAffected, mapError(result.Error)
+}
+
+func (r *SQLiteRollupRepo) Watermark(ctx context.Context, resolution string) (time.Time, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	return firstTime(db.Table(entities.RollupTable(resolution)).Select("bucket").Order("bucket DESC"))
+}
+
+func (r *SQLiteRollupRepo) OldestRawTimestamp(ctx context.Context, after time.Time) (time.Time, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	return firstTime(db.Table("sensor_readings").Select(`"timestamp"`).Where(`"timestamp" >= ?`, after.UTC()).Order(`"timestamp"`))
+}
+
+func (r *SQLiteRollupRepo) OldestRollup(ctx context.Context, resolution string, after time.Time) (time.Time, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	return firstTime(db.Table(entities.RollupTable(resolution)).Select("bucket").Where("bucket >= ?", after.UTC()).Order("bucket"))
+}
+
+// firstTime reads the first row's DATETIME column; aggregates like min() lose the column type
+// in SQLite and would come back as plain text.
+func firstTime(query *gorm.DB) (time.Time, error) {
+	var t sql.NullTime
+	err := query.Limit(1).Row().Scan(&t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return t.Time, mapError(err)
+}
+
+func (r *SQLiteRollupRepo) DeleteRawBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	return r.deleteInBatches(ctx, `
+DELETE FROM sensor_readings
+WHERE rowid IN (SELECT rowid FROM sensor_readings WHERE "timestamp" < @cutoff LIMIT @batch)`, cutoff, batchSize)
+}
+
+func (r *SQLiteRollupRepo) DeleteRollupsBefore(ctx context.Context, resolution string, cutoff time.Time, batchSize int) (int64, error) {
+	table := entities.RollupTable(resolution)
+	return r.deleteInBatches(ctx, `
+DELETE FROM `+table+`
+WHERE rowid IN (SELECT rowid FROM `+table+` WHERE bucket < @cutoff LIMIT @batch)`, cutoff, batchSize)
+}
+
+// deleteInBatches keeps each write transaction short so ingestion is not blocked behind a long purge.
+func (r *SQLiteRollupRepo) deleteInBatches(ctx context.Context, query string, cutoff time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		db, cancel := session(r.DB, ctx)
+		result := db.Exec(query, map[string]interface{}{"cutoff": cutoff.UTC(), "batch": batchSize})
+		cancel()
+		if result.Error != nil {
+			return total, mapError(result.Error)
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return total, nil
+		}
+	}
+}
+
+// Aggregate re-buckets rollups of the given resolution into the query's bucket width.
+func (r *SQLiteRollupRepo) Aggregate(ctx context.Context, resolution string, q entities.SensorAggregateQuery) ([]entities.SensorAggregate, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+
+	inner := `
+    SELECT device_id,
+           CAST(strftime('%s', bucket) AS INTEGER) / @bucket * @bucket AS b,
+           metric, min, max, sum, count, last, bucket
+    FROM ` + entities.RollupTable(resolution) + `
+    WHERE device_id IN @devices`
+	if !q.Start.IsZero() {
+		inner += ` AND bucket >= @start`
+	}
+	if !q.End.IsZero() {
+		inner += ` AND bucket <= @end`
+	}
+	if len(q.Metrics) > 0 {
+		inner += ` AND metric IN @metrics`
+	}
+
+	query := `
+SELECT device_id, b AS bucket, metric,
+       min(min) AS min,
+       max(max) AS max,
+       sum(sum) / NULLIF(sum(count), 0) AS avg,
+       max(bucket_last) AS last,
+       sum(count) AS count
+FROM (
+    SELECT m.*, first_value(last) OVER (PARTITION BY device_id, b, metric ORDER BY bucket DESC) AS bucket_last
+    FROM (` + inner + `) m
+)
+GROUP BY 1, 2, 3
+ORDER BY 2 ASC, 1, 3`
+
+	return scanSQLiteAggregates(db, query, sqliteAggregateArgs(q))
+}
+
+// SQLitePartitionRepo is a no-op: SQLite has no partitioning, so the retention job removes
+// expired readings and alerts with its batched deletes alone.
+type SQLitePartitionRepo struct{}
+
+func NewSQLitePartitionRepo() interfaces.PartitionRepository {
+	return &SQLitePartitionRepo{}
+}
+
+func (r *SQLitePartitionRepo) EnsureMonthlyPartitions(ctx context.Context, table string, from time.Time, monthsAhead int) error {
+	return nil
+}
+
+func (r *SQLitePartitionRepo) DropPartitionsBefore(ctx context.Context, table string, cutoff time.Time) ([]string, error) {
+	return nil, nil
+}
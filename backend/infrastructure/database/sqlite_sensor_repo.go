@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SQLiteSensorRepo stores readings in SQLite, which keeps payloads and times as text.
+type SQLiteSensorRepo struct {
+	DB *gorm.DB
+}
+
+func NewSQLiteSensorRepo(db *gorm.DB) interfaces.SensorRepository {
+	return &SQLiteSensorRepo{DB: db}
+}
+
+// sqliteReadingColumns reads the payload back as a BLOB, since the driver returns text
+// columns as strings that cannot be scanned into json.RawMessage.
+const sqliteReadingColumns = `id, device_id, sensor_type, CAST(payload AS BLOB) AS payload, "timestamp"`
+
+// sqlitePayloadMetricsSQL is the SQLite counterpart of payloadMetricsSQL: it expands r.payload
+// into (key, num) rows and is used as a table-valued function in the FROM clause.
+const sqlitePayloadMetricsSQL = `json_each(CASE WHEN json_type(r.payload) = 'object' THEN r.payload ELSE '{}' END) kv`
+
+const sqlitePayloadNumSQL = `CASE kv.type
+               WHEN 'integer' THEN kv.value
+               WHEN 'real' THEN kv.value
+               WHEN 'true' THEN 1
+               WHEN 'false' THEN 0
+           END`
+
+func (r *SQLiteSensorRepo) Create(ctx context.Context, reading *entities.SensorReading) error {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	if reading.ID == uuid.Nil {
+		reading.ID = uuid.New()
+	}
+	if reading.Timestamp.IsZero() {
+		reading.Timestamp = time.Now()
+	}
+	// Bind the payload as text; json.RawMessage would otherwise be stored as a BLOB the JSON functions reject
+	var payload interface{}
+	if reading.Payload != nil {
+		payload = string(reading.Payload)
+	}
+	err := db.Exec(`INSERT INTO sensor_readings (id, device_id, sensor_type, payload, "timestamp") VALUES (?, ?, ?, json(?), ?)`,
+		reading.ID, reading.DeviceID, reading.SensorType, payload, reading.Timestamp.UTC()).Error
+	return mapError(err)
+}
+
+func (r *SQLiteSensorRepo) FindByDeviceID(ctx context.Context, deviceID uuid.UUID) ([]entities.SensorReading, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var readings []entities.SensorReading
+	err := db.Select(sqliteReadingColumns).Where("device_id = ?", deviceID).Find(&readings).Error
+	return readings, mapError(err)
+}
+
+func (r *SQLiteSensorRepo) GetLatestByDeviceID(ctx context.Context, deviceID uuid.UUID) (*entities.SensorReading, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var reading entities.SensorReading
+	err := db.Select(sqliteReadingColumns).Where("device_id = ?", deviceID).Order("timestamp desc").First(&reading).Error
+	return &reading, mapError(err)
+}
+
+func (r *SQLiteSensorRepo) GetHistory(ctx context.Context, deviceID uuid.UUID, start, end string) ([]entities.SensorReading, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var readings []entities.SensorReading
+	query := db.Select(sqliteReadingColumns).Where("device_id = ?", deviceID)
+	// Times are compared as text, so bounds must be normalised to the stored UTC format
+	if start != "" {
+		t, err := time.Parse(time.RFC3339Nano, start)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("timestamp >= ?", t.UTC())
+	}
+	if end != "" {
+		t, err := time.Parse(time.RFC3339Nano, end)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("timestamp <= ?", t.UTC())
+	}
+	err := query.Order("timestamp asc").Find(&readings).Error
+	return readings, mapError(err)
+}
+
+// Aggregate computes the same buckets as SensorRepo.Aggregate using SQLite's JSON1 and window functions.
+func (r *SQLiteSensorRepo) Aggregate(ctx context.Context, q entities.SensorAggregateQuery) ([]entities.SensorAggregate, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+
+	inner := `
+    SELECT r.device_id,
+           CAST(strftime('%s', r."timestamp") AS INTEGER) / @bucket * @bucket AS bucket,
+           kv.key AS metric,
+           ` + sqlitePayloadNumSQL + ` AS num,
+           r."timestamp" AS ts
+    FROM sensor_readings r, ` + sqlitePayloadMetricsSQL + `
+    WHERE r.device_id IN @devices`
+	args := sqliteAggregateArgs(q)
+	if !q.Start.IsZero() {
+		inner += ` AND r."timestamp" >= @start`
+	}
+	if !q.End.IsZero() {
+		inner += ` AND r."timestamp" <= @end`
+	}
+	if len(q.Metrics) > 0 {
+		inner += ` AND kv.key IN @metrics`
+	}
+
+	query := `
+SELECT device_id, bucket, metric,
+       min(num) AS min,
+       max(num) AS max,
+       avg(num) AS avg,
+       max(last) AS last,
+       count(*) AS count
+FROM (
+    SELECT m.*, first_value(num) OVER (PARTITION BY device_id, bucket, metric ORDER BY ts DESC) AS last
+    FROM (` + inner + `) m
+    WHERE num IS NOT NULL
+)
+GROUP BY 1, 2, 3
+ORDER BY 2 ASC, 1, 3`
+
+	return scanSQLiteAggregates(db, query, args)
+}
+
+// sqliteAggregateRow is an aggregate whose bucket is computed as Unix seconds, since
+// SQLite only returns times for columns declared DATETIME.
+type sqliteAggregateRow struct {
+	DeviceID uuid.UUID
+	Bucket   int64
+	Metric   string
+	Min      float64
+	Max      float64
+	Avg      float64
+	Last     float64
+	Count    int64
+}
+
+func sqliteAggregateArgs(q entities.SensorAggregateQuery) map[string]interface{} {
+	bucketSeconds := int64(q.Bucket.Seconds())
+	if bucketSeconds < 1 {
+		bucketSeconds = 1
+	}
+	return map[string]interface{}{
+		"bucket":  bucketSeconds,
+		"devices": q.DeviceIDs,
+		"start":   q.Start.UTC(),
+		"end":     q.End.UTC(),
+		"metrics": q.Metrics,
+	}
+}
+
+func scanSQLiteAggregates(db *gorm.DB, query string, args map[string]interface{}) ([]entities.SensorAggregate, error) {
+	var rows []sqliteAggregateRow
+	if err := db.Raw(query, args).Scan(&rows).Error; err != nil {
+		return nil, mapError(err)
+	}
+	aggregates := make([]entities.SensorAggregate, 0, len(rows))
+	for _, row := range rows {
+		aggregates = append(aggregates, entities.SensorAggregate{
+			DeviceID: row.DeviceID,
+			Bucket:   time.Unix(row.Bucket, 0).UTC(),
+			Metric:   row.Metric,
+			Min:      row.Min,
+			Max:      row.Max,
+			Avg:      row.Avg,
+			Last:     row.Last,
+			Count:    row.Count,
+		})
+	}
+	return aggregates, nil
+}
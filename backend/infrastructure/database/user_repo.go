@@ -1,9 +1,11 @@
 package database
 
 import (
+	"context"
 	"minesense-backend/domain/entities"
 	"minesense-backend/domain/interfaces"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -15,16 +17,141 @@ func NewUserRepo(db *gorm.DB) interfaces.UserRepository {
 	return &UserRepo{DB: db}
 }
 
-func (r *UserRepo) Create(user *entities.User) error {
-	return r.DB.Create(user).Error
+func (r *UserRepo) Create(ctx context.Context, user *entities.User) error {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	return mapError(db.Create(user).Error)
 }
 
-func (r *UserRepo) FindByUsername(username string) (*entities.User, error) {
+// firstUserLockID is the advisory lock key serialising first-run registration across instances.
+const firstUserLockID = 7342190012
+
+func (r *UserRepo) CreateFirst(ctx context.Context, user *entities.User) (bool, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	created := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// SQLite is single-writer; Postgres transactions could both see no users without the lock
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", firstUserLockID).Error; err != nil {
+				return err
+			}
+		}
+		var count int64
+		if err := tx.Model(&entities.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		created = true
+		return tx.Create(user).Error
+	})
+	if err != nil {
+		return false, mapError(err)
+	}
+	return created, nil
+}
+
+func (r *UserRepo) FindByID(ctx context.Context, id uuid.UUID) (*entities.User, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
 	var user entities.User
-	err := r.DB.Where("username = ?", username).First(&user).Error
-	return &user, err
+	err := db.First(&user, "id = ?", id).Error
+	return &user, mapError(err)
+}
+
+func (r *UserRepo) FindByUsername(ctx context.Context, username string) (*entities.User, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var user entities.User
+	err := db.Where("username = ?", username).First(&user).Error
+	return &user, mapError(err)
+}
+
+func (r *UserRepo) FindAll(ctx context.Context) ([]entities.User, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var users []entities.User
+	err := db.Order("username").Find(&users).Error
+	return users, mapError(err)
+}
+
+func (r *UserRepo) Count(ctx context.Context) (int64, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	var count int64
+	err := db.Model(&entities.User{}).Count(&count).Error
+	return count, mapError(err)
+}
+
+func (r *UserRepo) Update(ctx context.Context, user *entities.User) error {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	return mapError(db.Save(user).Error)
+}
+
+// adminLockID is the advisory lock key serialising changes that could remove the last Admin.
+const adminLockID = 7342190013
+
+func (r *UserRepo) UpdateKeepingAdmin(ctx context.Context, user *entities.User) (bool, error) {
+	leaves := user.Role != entities.RoleAdmin || user.Disabled
+	return r.keepingAdmin(ctx, user.ID, leaves, func(tx *gorm.DB) error {
+		return tx.Save(user).Error
+	})
+}
+
+func (r *UserRepo) DeleteKeepingAdmin(ctx context.Context, id uuid.UUID) (bool, error) {
+	return r.keepingAdmin(ctx, id, true, func(tx *gorm.DB) error {
+		if err := tx.Model(&entities.Device{}).Where("supervisor_id = ?", id).Update("supervisor_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entities.Device{}).Where("worker_id = ?", id).Update("worker_id", nil).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&entities.User{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
 }
 
-func (r *UserRepo) Update(user *entities.User) error {
-	return r.DB.Save(user).Error
+// keepingAdmin runs change in a transaction unless the stored user is an enabled Admin, leaves
+// the enabled Admins with the change, and is the last one.
+func (r *UserRepo) keepingAdmin(ctx context.Context, id uuid.UUID, leaves bool, change func(tx *gorm.DB) error) (bool, error) {
+	db, cancel := session(r.DB, ctx)
+	defer cancel()
+	changed := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// SQLite is single-writer; Postgres transactions could each count the other's Admin without the lock
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", adminLockID).Error; err != nil {
+				return err
+			}
+		}
+		var stored entities.User
+		if err := tx.First(&stored, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if leaves && stored.Role == entities.RoleAdmin && !stored.Disabled {
+			var others int64
+			err := tx.Model(&entities.User{}).Where("role = ? AND NOT disabled AND id <> ?", entities.RoleAdmin, id).Count(&others).Error
+			if err != nil {
+				return err
+			}
+			if others == 0 {
+				return nil
+			}
+		}
+		changed = true
+		return change(tx)
+	})
+	if err != nil {
+		return false, mapError(err)
+	}
+	return changed, nil
 }
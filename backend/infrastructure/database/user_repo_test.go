@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/usecases"
+)
+
+func TestLastAdminSurvivesConcurrentChanges(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepo(openTestSQLite(t))
+	// Two use cases over one repository stand in for two server instances
+	first, second := usecases.NewUserUseCase(repo), usecases.NewUserUseCase(repo)
+
+	a, err := first.CreateUser(ctx, "admin-a", "password-a", entities.RoleAdmin)
+	if err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+	b, err := first.CreateUser(ctx, "admin-b", "password-b", entities.RoleAdmin)
+	if err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+
+	// Each instance takes out a different Admin at the same time; one must be refused
+	errs := make(chan error, 2)
+	go func() {
+		_, err := first.UpdateRole(ctx, a.ID, entities.RoleWorker)
+		errs <- err
+	}()
+	go func() {
+		_, err := second.SetDisabled(ctx, b.ID, true)
+		errs <- err
+	}()
+	refused := 0
+	for i := 0; i < 2; i++ {
+		err := <-errs
+		if errors.Is(err, usecases.ErrLastAdmin) {
+			refused++
+		} else if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if refused != 1 {
+		t.Fatalf("%d changes refused, want 1", refused)
+	}
+
+	// The survivor cannot be deleted either, but anyone else can
+	survivor, other := a, b
+	if stored, err := repo.FindByID(ctx, b.ID); err == nil && stored.Role == entities.RoleAdmin && !stored.Disabled {
+		survivor, other = b, a
+	}
+	if err := second.DeleteUser(ctx, survivor.ID); !errors.Is(err, usecases.ErrLastAdmin) {
+		t.Fatalf("deleting the last Admin: got %v, want ErrLastAdmin", err)
+	}
+	if err := second.DeleteUser(ctx, other.ID); err != nil {
+		t.Fatalf("deleting a former Admin: %v", err)
+	}
+}
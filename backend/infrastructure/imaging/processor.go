@@ -0,0 +1,22 @@
+package imaging
+
+import (
+	"bytes"
+	"image/jpeg"
+)
+
+// Processor implements interfaces.ImageProcessor for JPEG frames.
+type Processor struct{}
+
+// Dimensions reads a JPEG's size from its header without decoding the pixels.
+func (Processor) Dimensions(data []byte) (int, int, error) {
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+func (Processor) Thumbnail(data []byte, width int) ([]byte, error) {
+	return Thumbnail(data, width)
+}
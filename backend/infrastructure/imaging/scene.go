@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+
+	"minesense-backend/domain/interfaces"
+)
+
+// A scene is summarized as the mean luminance of each cell of a sceneCols x sceneRows grid,
+// coarse enough to ignore sensor noise and JPEG artifacts.
+const (
+	sceneCols = 32
+	sceneRows = 24
+)
+
+// changeLevel is how far (of 255) a cell's luminance must move to count as changed.
+const changeLevel = 24
+
+// Scene is a coarse luminance summary of a frame, for comparing frames of the same camera.
+type Scene struct {
+	cells [sceneCols * sceneRows]uint8
+	// brightness is the mean luminance (0-1); contrast is the standard deviation of the cell
+	// luminances (0-1), near zero when the lens is covered.
+	brightness float64
+	contrast   float64
+}
+
+// SceneAnalyzer implements interfaces.SceneAnalyzer with AnalyzeScene.
+type SceneAnalyzer struct{}
+
+func (SceneAnalyzer) AnalyzeScene(data []byte) (interfaces.Scene, error) {
+	return AnalyzeScene(data)
+}
+
+func (s *Scene) Brightness() float64 { return s.brightness }
+func (s *Scene) Contrast() float64   { return s.contrast }
+
+// AnalyzeScene decodes a JPEG and summarizes its luminance.
+func AnalyzeScene(data []byte) (*Scene, error) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// JPEGs decode to YCbCr or Gray, whose Y plane is the luminance; anything else is converted
+	luma := func(x, y int) uint8 { return color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y }
+	switch m := img.(type) {
+	case *image.YCbCr:
+		luma = func(x, y int) uint8 { return m.Y[m.YOffset(b.Min.X+x, b.Min.Y+y)] }
+	case *image.Gray:
+		luma = func(x, y int) uint8 { return m.Pix[m.PixOffset(b.Min.X+x, b.Min.Y+y)] }
+	}
+
+	var sums, counts [sceneCols * sceneRows]int
+	for y := 0; y < h; y++ {
+		row := y * sceneRows / h * sceneCols
+		for x := 0; x < w; x++ {
+			cell := row + x*sceneCols/w
+			sums[cell] += int(luma(x, y))
+			counts[cell]++
+		}
+	}
+
+	s := &Scene{}
+	var total, squares float64
+	n := 0
+	for i := range s.cells {
+		if counts[i] == 0 {
+			continue // Images smaller than the grid leave cells empty
+		}
+		v := float64(sums[i]) / float64(counts[i])
+		s.cells[i] = uint8(v)
+		total += v
+		squares += v * v
+		n++
+	}
+	mean := total / float64(n)
+	s.brightness = mean / 255
+	s.contrast = math.Sqrt(max(squares/float64(n)-mean*mean, 0)) / 255
+	return s, nil
+}
+
+// Difference is the fraction of the scene (0-1) that changed since prev. Scenes from another
+// analyzer have nothing in common with s and count as fully changed.
+func (s *Scene) Difference(other interfaces.Scene) float64 {
+	prev, ok := other.(*Scene)
+	if !ok {
+		return 1
+	}
+	changed := 0
+	for i := range s.cells {
+		d := int(s.cells[i]) - int(prev.cells[i])
+		if d > changeLevel || d < -changeLevel {
+			changed++
+		}
+	}
+	return float64(changed) / float64(len(s.cells))
+}
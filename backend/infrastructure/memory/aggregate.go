@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"minesense-backend/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+// payloadMetrics extracts numeric payload values, and booleans as 0/1, matching the
+// Postgres aggregation over JSONB.
+func payloadMetrics(payload json.RawMessage) map[string]float64 {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil
+	}
+	metrics := make(map[string]float64, len(fields))
+	for key, value := range fields {
+		switch v := value.(type) {
+		case float64:
+			metrics[key] = v
+		case bool:
+			if v {
+				metrics[key] = 1
+			} else {
+				metrics[key] = 0
+			}
+		}
+	}
+	return metrics
+}
+
+// bucketStart floors t to a multiple of width since the Unix epoch, like to_timestamp(floor(epoch / width) * width).
+func bucketStart(t time.Time, width time.Duration) time.Time {
+	seconds := int64(width.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	unix := t.Unix()
+	floored := unix - unix%seconds
+	if unix < 0 && unix%seconds != 0 {
+		floored -= seconds
+	}
+	return time.Unix(floored, 0).UTC()
+}
+
+// accumulator folds values (or already-aggregated rollups) into a single bucket.
+type accumulator struct {
+	min, max, sum float64
+	last          float64
+	lastAt        time.Time
+	count         int64
+}
+
+func (a *accumulator) add(min, max, sum, last float64, at time.Time, count int64) {
+	if a.count == 0 || min < a.min {
+		a.min = min
+	}
+	if a.count == 0 || max > a.max {
+		a.max = max
+	}
+	if a.count == 0 || !at.Before(a.lastAt) {
+		a.last = last
+		a.lastAt = at
+	}
+	a.sum += sum
+	a.count += count
+}
+
+type buckets map[rollupKey]*accumulator
+
+func (b buckets) add(key rollupKey, min, max, sum, last float64, at time.Time, count int64) {
+	acc, ok := b[key]
+	if !ok {
+		acc = &accumulator{}
+		b[key] = acc
+	}
+	acc.add(min, max, sum, last, at, count)
+}
+
+func (b buckets) sortedKeys() []rollupKey {
+	keys := make([]rollupKey, 0, len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].Bucket.Equal(keys[j].Bucket) {
+			return keys[i].Bucket.Before(keys[j].Bucket)
+		}
+		if keys[i].DeviceID != keys[j].DeviceID {
+			return keys[i].DeviceID.String() < keys[j].DeviceID.String()
+		}
+		return keys[i].Metric < keys[j].Metric
+	})
+	return keys
+}
+
+// aggregates returns the buckets ordered by bucket, device and metric.
+func (b buckets) aggregates() []entities.SensorAggregate {
+	out := []entities.SensorAggregate{}
+	for _, k := range b.sortedKeys() {
+		acc := b[k]
+		out = append(out, entities.SensorAggregate{
+			DeviceID: k.DeviceID,
+			Bucket:   k.Bucket,
+			Metric:   k.Metric,
+			Min:      acc.min,
+			Max:      acc.max,
+			Avg:      acc.sum / float64(acc.count),
+			Last:     acc.last,
+			Count:    acc.count,
+		})
+	}
+	return out
+}
+
+func (b buckets) rollups() []entities.SensorRollup {
+	var out []entities.SensorRollup
+	for _, k := range b.sortedKeys() {
+		acc := b[k]
+		out = append(out, entities.SensorRollup{
+			DeviceID: k.DeviceID,
+			Bucket:   k.Bucket,
+			Metric:   k.Metric,
+			Min:      acc.min,
+			Max:      acc.max,
+			Sum:      acc.sum,
+			Last:     acc.last,
+			Count:    acc.count,
+		})
+	}
+	return out
+}
+
+// queryFilter applies the device, time range and metric filters of an aggregate query.
+type queryFilter struct {
+	devices map[uuid.UUID]bool
+	metrics map[string]bool
+	start   time.Time
+	end     time.Time
+}
+
+func newQueryFilter(q entities.SensorAggregateQuery) queryFilter {
+	f := queryFilter{devices: map[uuid.UUID]bool{}, start: q.Start, end: q.End}
+	for _, id := range q.DeviceIDs {
+		f.devices[id] = true
+	}
+	if len(q.Metrics) > 0 {
+		f.metrics = map[string]bool{}
+		for _, m := range q.Metrics {
+			f.metrics[m] = true
+		}
+	}
+	return f
+}
+
+func (f queryFilter) matchRow(deviceID uuid.UUID, at time.Time) bool {
+	if !f.devices[deviceID] {
+		return false
+	}
+	if !f.start.IsZero() && at.Before(f.start) {
+		return false
+	}
+	if !f.end.IsZero() && at.After(f.end) {
+		return false
+	}
+	return true
+}
+
+func (f queryFilter) matchMetric(metric string) bool {
+	return f.metrics == nil || f.metrics[metric]
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/usecases"
+
+	"github.com/google/uuid"
+)
+
+func TestAggregateIncludesReadingsNewerThanRollups(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	sensors := NewSensorRepo(store)
+	rollups := NewRollupRepo(store)
+	deviceID := uuid.New()
+	now := time.Now()
+
+	record := func(at time.Time, gas float64) {
+		payload, _ := json.Marshal(map[string]float64{"gas": gas})
+		reading := &entities.SensorReading{DeviceID: deviceID, SensorType: "helmet", Payload: payload, Timestamp: at}
+		if err := sensors.Create(ctx, reading); err != nil {
+			t.Fatalf("create reading: %v", err)
+		}
+	}
+	record(now.Add(-5*time.Hour), 100)
+	record(now.Add(-90*time.Minute), 200)
+	record(now.Add(-10*time.Minute), 300)
+
+	policy := usecases.RetentionPolicy{}
+	usecases.NewRetentionUseCase(rollups, NewPartitionRepo(), NewAlertRepo(store), policy).RunOnce(ctx)
+
+	// Not rolled up yet: inside the rollup lag
+	record(now.Add(-30*time.Second), 999)
+
+	uc := usecases.NewSensorUseCase(sensors, NewAlertRepo(store), rollups, policy)
+	cases := []struct {
+		name   string
+		bucket string
+		span   time.Duration
+	}{
+		{"minute rollups", "1h", 3 * 24 * time.Hour},
+		{"hour rollups", "1d", 10 * 24 * time.Hour},
+	}
+	for _, c := range cases {
+		aggregates, err := uc.Aggregate(ctx, []uuid.UUID{deviceID}, c.bucket, now.Add(-c.span), now, []string{"gas"})
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var count int64
+		var max, last float64
+		for _, a := range aggregates {
+			count += a.Count
+			if a.Max > max {
+				max = a.Max
+			}
+			last = a.Last
+		}
+		if count != 4 || max != 999 || last != 999 {
+			t.Errorf("%s: count %d, max %v, last %v; want 4 readings including the newest (999)", c.name, count, max, last)
+		}
+	}
+}
+
+func TestHistoryServesExpiredRangeFromRollups(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	sensors := NewSensorRepo(store)
+	rollups := NewRollupRepo(store)
+	deviceID := uuid.New()
+	now := time.Now()
+
+	record := func(at time.Time, gas float64) {
+		payload, _ := json.Marshal(map[string]float64{"gas": gas})
+		reading := &entities.SensorReading{DeviceID: deviceID, SensorType: "helmet", Payload: payload, Timestamp: at}
+		if err := sensors.Create(ctx, reading); err != nil {
+			t.Fatalf("create reading: %v", err)
+		}
+	}
+	old := now.Add(-3 * time.Hour).Truncate(time.Minute)
+	record(old.Add(5*time.Second), 100)
+	record(old.Add(15*time.Second), 200)
+	record(now.Add(-10*time.Minute), 300)
+
+	// Rolls everything up, then deletes the raw readings older than an hour
+	policy := usecases.RetentionPolicy{RawRetention: time.Hour}
+	usecases.NewRetentionUseCase(rollups, NewPartitionRepo(), NewAlertRepo(store), policy).RunOnce(ctx)
+
+	uc := usecases.NewSensorUseCase(sensors, NewAlertRepo(store), rollups, policy)
+	readings, err := uc.GetHistory(ctx, deviceID, now.Add(-4*time.Hour).Format(time.RFC3339), "")
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if len(readings) != 2 {
+		t.Fatalf("GetHistory returned %d readings, want one rolled-up minute and one raw reading", len(readings))
+	}
+	var rolled map[string]float64
+	json.Unmarshal(readings[0].Payload, &rolled)
+	if readings[0].SensorType != "rollup_1m" || !readings[0].Timestamp.Equal(old) || rolled["gas"] != 150 {
+		t.Errorf("rolled-up reading: %s at %v with %s, want rollup_1m at %v with gas 150",
+			readings[0].SensorType, readings[0].Timestamp, readings[0].Payload, old)
+	}
+	if readings[1].SensorType != "helmet" {
+		t.Errorf("recent reading has sensor type %s, want the raw reading", readings[1].SensorType)
+	}
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+type AlertRepo struct {
+	Store *Store
+}
+
+func NewAlertRepo(store *Store) interfaces.AlertRepository {
+	return &AlertRepo{Store: store}
+}
+
+func (r *AlertRepo) Create(ctx context.Context, alert *entities.Alert) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	stamp(&alert.ID, &alert.CreatedAt)
+	stored := *alert
+	stored.Device = entities.Device{}
+	r.Store.alerts = append(r.Store.alerts, stored)
+	return nil
+}
+
+func (r *AlertRepo) FindAll(ctx context.Context) ([]entities.Alert, error) {
+	return r.find(ctx, func(entities.Alert) bool { return true })
+}
+
+func (r *AlertRepo) FindByID(ctx context.Context, id uuid.UUID) (*entities.Alert, error) {
+	alerts, err := r.find(ctx, func(a entities.Alert) bool { return a.ID == id })
+	if err != nil {
+		return &entities.Alert{}, err
+	}
+	if len(alerts) == 0 {
+		return &entities.Alert{}, interfaces.ErrNotFound
+	}
+	return &alerts[0], nil
+}
+
+func (r *AlertRepo) FindByDeviceID(ctx context.Context, deviceID uuid.UUID) ([]entities.Alert, error) {
+	return r.find(ctx, func(a entities.Alert) bool { return a.DeviceID == deviceID })
+}
+
+func (r *AlertRepo) FindBetween(ctx context.Context, since, until time.Time) ([]entities.Alert, error) {
+	alerts, err := r.find(ctx, func(a entities.Alert) bool {
+		return a.CreatedAt.After(since) && !a.CreatedAt.After(until)
+	})
+	sort.SliceStable(alerts, func(i, j int) bool { return alerts[i].CreatedAt.Before(alerts[j].CreatedAt) })
+	return alerts, err
+}
+
+// DeleteBefore ignores batchSize: there are no table locks to keep short in memory.
+func (r *AlertRepo) DeleteBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	kept := r.Store.alerts[:0]
+	for _, a := range r.Store.alerts {
+		if !a.CreatedAt.Before(cutoff) {
+			kept = append(kept, a)
+		}
+	}
+	deleted := int64(len(r.Store.alerts) - len(kept))
+	r.Store.alerts = kept
+	return deleted, nil
+}
+
+func (r *AlertRepo) find(ctx context.Context, match func(entities.Alert) bool) ([]entities.Alert, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	alerts := []entities.Alert{}
+	for _, a := range r.Store.alerts {
+		if match(a) {
+			// Equivalent of Preload("Device")
+			a.Device = r.Store.devices[a.DeviceID]
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
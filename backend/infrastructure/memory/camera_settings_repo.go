@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+type CameraSettingsRepo struct {
+	Store *Store
+}
+
+func NewCameraSettingsRepo(store *Store) interfaces.CameraSettingsRepository {
+	return &CameraSettingsRepo{Store: store}
+}
+
+func (r *CameraSettingsRepo) FindByDeviceID(ctx context.Context, deviceID uuid.UUID) (*entities.CameraSettings, error) {
+	if err := checkContext(ctx); err != nil {
+		return &entities.CameraSettings{}, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	settings, ok := r.Store.cameras[deviceID]
+	if !ok {
+		return &settings, interfaces.ErrNotFound
+	}
+	return &settings, nil
+}
+
+func (r *CameraSettingsRepo) FindAll(ctx context.Context) ([]entities.CameraSettings, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	all := []entities.CameraSettings{}
+	for _, settings := range r.Store.cameras {
+		all = append(all, settings)
+	}
+	return all, nil
+}
+
+func (r *CameraSettingsRepo) Save(ctx context.Context, settings *entities.CameraSettings) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	settings.UpdatedAt = time.Now()
+	r.Store.cameras[settings.DeviceID] = *settings
+	return nil
+}
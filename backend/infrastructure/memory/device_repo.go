@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+type DeviceRepo struct {
+	Store *Store
+}
+
+func NewDeviceRepo(store *Store) interfaces.DeviceRepository {
+	return &DeviceRepo{Store: store}
+}
+
+func (r *DeviceRepo) Create(ctx context.Context, device *entities.Device) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	stamp(&device.ID, &device.CreatedAt)
+	if _, exists := r.Store.devices[device.ID]; exists {
+		return interfaces.ErrDuplicate
+	}
+	device.UpdatedAt = device.CreatedAt
+	r.Store.devices[device.ID] = *device
+	return nil
+}
+
+func (r *DeviceRepo) FindByID(ctx context.Context, id uuid.UUID) (*entities.Device, error) {
+	if err := checkContext(ctx); err != nil {
+		return &entities.Device{}, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	device, ok := r.Store.devices[id]
+	if !ok {
+		return &device, interfaces.ErrNotFound
+	}
+	return &device, nil
+}
+
+func (r *DeviceRepo) FindAll(ctx context.Context) ([]entities.Device, error) {
+	return r.find(ctx, func(entities.Device) bool { return true })
+}
+
+func (r *DeviceRepo) FindByLocation(ctx context.Context, location string) ([]entities.Device, error) {
+	return r.find(ctx, func(d entities.Device) bool { return d.Location == location })
+}
+
+func (r *DeviceRepo) Update(ctx context.Context, device *entities.Device) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	// Like gorm's Save, an unknown ID is inserted
+	stamp(&device.ID, &device.CreatedAt)
+	device.UpdatedAt = time.Now()
+	r.Store.devices[device.ID] = *device
+	return nil
+}
+
+func (r *DeviceRepo) find(ctx context.Context, match func(entities.Device) bool) ([]entities.Device, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	devices := []entities.Device{}
+	for _, d := range r.Store.devices {
+		if match(d) {
+			devices = append(devices, d)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].CreatedAt.Before(devices[j].CreatedAt) })
+	return devices, nil
+}
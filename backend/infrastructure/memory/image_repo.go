@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+type ImageRepo struct {
+	Store *Store
+}
+
+func NewImageRepo(store *Store) interfaces.ImageRepository {
+	return &ImageRepo{Store: store}
+}
+
+func (r *ImageRepo) Create(ctx context.Context, image *entities.Image) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	stamp(&image.ID, &image.Timestamp)
+	if _, exists := r.Store.images[image.ID]; exists {
+		return interfaces.ErrDuplicate
+	}
+	stored := *image
+	stored.ImageURL = ""
+	r.Store.images[image.ID] = stored
+	return nil
+}
+
+func (r *ImageRepo) FindByID(ctx context.Context, id uuid.UUID) (*entities.Image, error) {
+	if err := checkContext(ctx); err != nil {
+		return &entities.Image{}, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	image, ok := r.Store.images[id]
+	if !ok {
+		return &image, interfaces.ErrNotFound
+	}
+	return &image, nil
+}
+
+func (r *ImageRepo) FindByDevice(ctx context.Context, deviceID uuid.UUID, start, end time.Time, limit int) ([]entities.Image, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	images := []entities.Image{}
+	for _, image := range r.Store.images {
+		if image.DeviceID == deviceID && !image.Timestamp.Before(start) && !image.Timestamp.After(end) {
+			images = append(images, image)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Timestamp.After(images[j].Timestamp) })
+	if limit > 0 && len(images) > limit {
+		images = images[:limit]
+	}
+	return images, nil
+}
+
+func (r *ImageRepo) FindMotion(ctx context.Context, deviceID uuid.UUID, start, end time.Time, minScore float64, limit int) ([]entities.Image, error) {
+	images, err := r.FindByDevice(ctx, deviceID, start, end, 0)
+	if err != nil {
+		return nil, err
+	}
+	moving := []entities.Image{}
+	for _, image := range images {
+		if image.MotionScore != nil && *image.MotionScore >= minScore {
+			moving = append(moving, image)
+		}
+	}
+	if limit > 0 && len(moving) > limit {
+		moving = moving[:limit]
+	}
+	return moving, nil
+}
+
+func (r *ImageRepo) Usage(ctx context.Context, deviceID uuid.UUID) (int64, int64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, 0, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	var frames, bytes int64
+	for _, image := range r.Store.images {
+		if image.DeviceID == deviceID {
+			frames++
+			bytes += image.SizeBytes
+		}
+	}
+	return frames, bytes, nil
+}
+
+func (r *ImageRepo) LinkToAlert(ctx context.Context, alertID uuid.UUID, imageIDs []uuid.UUID) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	linked, ok := r.Store.evidence[alertID]
+	if !ok {
+		linked = make(map[uuid.UUID]bool)
+		r.Store.evidence[alertID] = linked
+	}
+	for _, id := range imageIDs {
+		linked[id] = true
+	}
+	return nil
+}
+
+func (r *ImageRepo) FindByAlert(ctx context.Context, alertID uuid.UUID) ([]entities.Image, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	images := []entities.Image{}
+	for id := range r.Store.evidence[alertID] {
+		if image, ok := r.Store.images[id]; ok {
+			images = append(images, image)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Timestamp.Before(images[j].Timestamp) })
+	return images, nil
+}
+
+func (r *ImageRepo) ScanByDevice(ctx context.Context, deviceID uuid.UUID, from, end time.Time, limit int) ([]entities.Image, error) {
+	return r.findAscending(ctx, limit, func(image entities.Image) bool {
+		return image.DeviceID == deviceID && !image.Timestamp.Before(from) && !image.Timestamp.After(end)
+	})
+}
+
+func (r *ImageRepo) FindExpired(ctx context.Context, deviceID uuid.UUID, cutoff time.Time, limit int) ([]entities.Image, error) {
+	r.Store.mu.RLock()
+	// Links outlive alerts removed by alert retention, so only links to existing alerts count
+	alerts := map[uuid.UUID]bool{}
+	for _, alert := range r.Store.alerts {
+		alerts[alert.ID] = true
+	}
+	evidence := map[uuid.UUID]bool{}
+	for alertID, linked := range r.Store.evidence {
+		if !alerts[alertID] {
+			continue
+		}
+		for id := range linked {
+			evidence[id] = true
+		}
+	}
+	r.Store.mu.RUnlock()
+	return r.findAscending(ctx, limit, func(image entities.Image) bool {
+		return image.DeviceID == deviceID && image.Timestamp.Before(cutoff) && !evidence[image.ID]
+	})
+}
+
+func (r *ImageRepo) findAscending(ctx context.Context, limit int, match func(entities.Image) bool) ([]entities.Image, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	images := []entities.Image{}
+	for _, image := range r.Store.images {
+		if match(image) {
+			images = append(images, image)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Timestamp.Before(images[j].Timestamp) })
+	if limit > 0 && len(images) > limit {
+		images = images[:limit]
+	}
+	return images, nil
+}
+
+func (r *ImageRepo) Delete(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	var deleted int64
+	for _, id := range ids {
+		if _, ok := r.Store.images[id]; ok {
+			delete(r.Store.images, id)
+			deleted++
+		}
+	}
+	// Links go away with their image, like ON DELETE CASCADE
+	for _, linked := range r.Store.evidence {
+		for _, id := range ids {
+			delete(linked, id)
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"minesense-backend/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+func TestFindExpiredSkipsEvidenceOfExistingAlerts(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	images := NewImageRepo(store)
+	deviceID := uuid.New()
+
+	alert := &entities.Alert{DeviceID: deviceID, AlertType: "Man-Down", Severity: "High", Message: "fall", CreatedAt: time.Now()}
+	if err := NewAlertRepo(store).Create(ctx, alert); err != nil {
+		t.Fatalf("create alert: %v", err)
+	}
+
+	now := time.Now()
+	frame := func(age time.Duration) *entities.Image {
+		image := &entities.Image{DeviceID: deviceID, StorageKey: uuid.NewString(), Timestamp: now.Add(-age)}
+		if err := images.Create(ctx, image); err != nil {
+			t.Fatalf("create image: %v", err)
+		}
+		return image
+	}
+	expired := frame(3 * time.Hour)
+	evidence := frame(2 * time.Hour)
+	orphaned := frame(90 * time.Minute) // Linked to an alert that has since been dropped
+	frame(time.Minute)
+
+	images.LinkToAlert(ctx, alert.ID, []uuid.UUID{evidence.ID})
+	images.LinkToAlert(ctx, uuid.New(), []uuid.UUID{orphaned.ID})
+
+	found, err := images.FindExpired(ctx, deviceID, now.Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatalf("FindExpired: %v", err)
+	}
+	if len(found) != 2 || found[0].ID != expired.ID || found[1].ID != orphaned.ID {
+		t.Fatalf("FindExpired returned %d frames, want the expired and orphaned frames", len(found))
+	}
+}
@@ -0,0 +1,24 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"minesense-backend/domain/interfaces"
+)
+
+// PartitionRepo is a no-op: the in-memory store has no partitions, so the retention job
+// removes expired readings and alerts with its batched deletes alone.
+type PartitionRepo struct{}
+
+func NewPartitionRepo() interfaces.PartitionRepository {
+	return &PartitionRepo{}
+}
+
+func (r *PartitionRepo) EnsureMonthlyPartitions(ctx context.Context, table string, from time.Time, monthsAhead int) error {
+	return checkContext(ctx)
+}
+
+func (r *PartitionRepo) DropPartitionsBefore(ctx context.Context, table string, cutoff time.Time) ([]string, error) {
+	return nil, checkContext(ctx)
+}
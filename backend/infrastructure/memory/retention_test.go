@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/usecases"
+
+	"github.com/google/uuid"
+)
+
+func TestRetentionExpiresAlertsWithoutPartitions(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	alerts := NewAlertRepo(store)
+
+	deviceID := uuid.New()
+	now := time.Now()
+	for _, age := range []time.Duration{90 * 24 * time.Hour, 31 * 24 * time.Hour, time.Hour} {
+		alert := &entities.Alert{DeviceID: deviceID, AlertType: "Gas Hazard", Severity: "High", Message: "gas", CreatedAt: now.Add(-age)}
+		if err := alerts.Create(ctx, alert); err != nil {
+			t.Fatalf("create alert: %v", err)
+		}
+	}
+
+	retention := usecases.NewRetentionUseCase(NewRollupRepo(store), NewPartitionRepo(), alerts, usecases.RetentionPolicy{
+		AlertRetention: 30 * 24 * time.Hour,
+	})
+	retention.RunOnce(ctx)
+
+	left, err := alerts.FindAll(ctx)
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if len(left) != 1 || left[0].CreatedAt.Before(now.Add(-2*time.Hour)) {
+		t.Fatalf("%d alerts left after retention, want only the recent one", len(left))
+	}
+}
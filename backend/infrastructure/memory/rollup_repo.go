@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+)
+
+type RollupRepo struct {
+	Store *Store
+}
+
+func NewRollupRepo(store *Store) interfaces.SensorRollupRepository {
+	return &RollupRepo{Store: store}
+}
+
+func (r *RollupRepo) table(resolution string) (map[rollupKey]entities.SensorRollup, error) {
+	t, ok := r.Store.rollups[resolution]
+	if !ok {
+		return nil, fmt.Errorf("unknown rollup resolution %q", resolution)
+	}
+	return t, nil
+}
+
+func (r *RollupRepo) RollupRaw(ctx context.Context, from, to time.Time) (int64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	b := buckets{}
+	for _, s := range r.Store.readings {
+		if s.Timestamp.Before(from) || !s.Timestamp.Before(to) {
+			continue
+		}
+		bucket := s.Timestamp.UTC().Truncate(time.Minute)
+		for metric, v := range payloadMetrics(s.Payload) {
+			b.add(rollupKey{s.DeviceID, bucket, metric}, v, v, v, v, s.Timestamp, 1)
+		}
+	}
+	return r.upsert(entities.RollupMinute, b), nil
+}
+
+func (r *RollupRepo) RollupMinutes(ctx context.Context, from, to time.Time) (int64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	b := buckets{}
+	for k, m := range r.Store.rollups[entities.RollupMinute] {
+		if k.Bucket.Before(from) || !k.Bucket.Before(to) {
+			continue
+		}
+		hour := rollupKey{k.DeviceID, k.Bucket.Truncate(time.Hour), k.Metric}
+		b.add(hour, m.Min, m.Max, m.Sum, m.Last, m.Bucket, m.Count)
+	}
+	return r.upsert(entities.RollupHour, b), nil
+}
+
+// upsert replaces existing rollups with the recomputed buckets. Callers hold the write lock.
+func (r *RollupRepo) upsert(resolution string, b buckets) int64 {
+	t := r.Store.rollups[resolution]
+	for _, rollup := range b.rollups() {
+		t[rollupKey{rollup.DeviceID, rollup.Bucket, rollup.Metric}] = rollup
+	}
+	return int64(len(b))
+}
+
+func (r *RollupRepo) Watermark(ctx context.Context, resolution string) (time.Time, error) {
+	if err := checkContext(ctx); err != nil {
+		return time.Time{}, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	t, err := r.table(resolution)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var latest time.Time
+	for k := range t {
+		if k.Bucket.After(latest) {
+			latest = k.Bucket
+		}
+	}
+	return latest, nil
+}
+
+func (r *RollupRepo) OldestRawTimestamp(ctx context.Context, after time.Time) (time.Time, error) {
+	if err := checkContext(ctx); err != nil {
+		return time.Time{}, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	var oldest time.Time
+	for _, s := range r.Store.readings {
+		if !s.Timestamp.Before(after) && (oldest.IsZero() || s.Timestamp.Before(oldest)) {
+			oldest = s.Timestamp
+		}
+	}
+	return oldest, nil
+}
+
+func (r *RollupRepo) OldestRollup(ctx context.Context, resolution string, after time.Time) (time.Time, error) {
+	if err := checkContext(ctx); err != nil {
+		return time.Time{}, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	t, err := r.table(resolution)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var oldest time.Time
+	for k := range t {
+		if !k.Bucket.Before(after) && (oldest.IsZero() || k.Bucket.Before(oldest)) {
+			oldest = k.Bucket
+		}
+	}
+	return oldest, nil
+}
+
+// DeleteRawBefore ignores batchSize: there are no table locks to keep short in memory.
+func (r *RollupRepo) DeleteRawBefore(ctx context.Context, cutoff time.Time, batchSize int) (int64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	kept := r.Store.readings[:0]
+	for _, s := range r.Store.readings {
+		if !s.Timestamp.Before(cutoff) {
+			kept = append(kept, s)
+		}
+	}
+	deleted := int64(len(r.Store.readings) - len(kept))
+	r.Store.readings = kept
+	return deleted, nil
+}
+
+func (r *RollupRepo) DeleteRollupsBefore(ctx context.Context, resolution string, cutoff time.Time, batchSize int) (int64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	t, err := r.table(resolution)
+	if err != nil {
+		return 0, err
+	}
+	var deleted int64
+	for k := range t {
+		if k.Bucket.Before(cutoff) {
+			delete(t, k)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Aggregate re-buckets rollups of the given resolution into the query's bucket width.
+func (r *RollupRepo) Aggregate(ctx context.Context, resolution string, q entities.SensorAggregateQuery) ([]entities.SensorAggregate, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	t, err := r.table(resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := newQueryFilter(q)
+	b := buckets{}
+	for k, m := range t {
+		if !filter.matchRow(k.DeviceID, k.Bucket) || !filter.matchMetric(k.Metric) {
+			continue
+		}
+		bucket := rollupKey{k.DeviceID, bucketStart(k.Bucket, q.Bucket), k.Metric}
+		b.add(bucket, m.Min, m.Max, m.Sum, m.Last, m.Bucket, m.Count)
+	}
+	return b.aggregates(), nil
+}
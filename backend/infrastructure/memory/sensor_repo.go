@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+type SensorRepo struct {
+	Store *Store
+}
+
+func NewSensorRepo(store *Store) interfaces.SensorRepository {
+	return &SensorRepo{Store: store}
+}
+
+func (r *SensorRepo) Create(ctx context.Context, reading *entities.SensorReading) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	stamp(&reading.ID, &reading.Timestamp)
+	stored := *reading
+	stored.Device = entities.Device{}
+	r.Store.readings = append(r.Store.readings, stored)
+	return nil
+}
+
+func (r *SensorRepo) FindByDeviceID(ctx context.Context, deviceID uuid.UUID) ([]entities.SensorReading, error) {
+	return r.find(ctx, func(s entities.SensorReading) bool { return s.DeviceID == deviceID })
+}
+
+func (r *SensorRepo) GetLatestByDeviceID(ctx context.Context, deviceID uuid.UUID) (*entities.SensorReading, error) {
+	readings, err := r.FindByDeviceID(ctx, deviceID)
+	if err != nil {
+		return &entities.SensorReading{}, err
+	}
+	if len(readings) == 0 {
+		return &entities.SensorReading{}, interfaces.ErrNotFound
+	}
+	return &readings[len(readings)-1], nil
+}
+
+func (r *SensorRepo) GetHistory(ctx context.Context, deviceID uuid.UUID, start, end string) ([]entities.SensorReading, error) {
+	var from, to time.Time
+	var err error
+	if start != "" {
+		if from, err = time.Parse(time.RFC3339Nano, start); err != nil {
+			return nil, err
+		}
+	}
+	if end != "" {
+		if to, err = time.Parse(time.RFC3339Nano, end); err != nil {
+			return nil, err
+		}
+	}
+	return r.find(ctx, func(s entities.SensorReading) bool {
+		return s.DeviceID == deviceID &&
+			(from.IsZero() || !s.Timestamp.Before(from)) &&
+			(to.IsZero() || !s.Timestamp.After(to))
+	})
+}
+
+// Aggregate computes the same buckets as the Postgres implementation by scanning readings in memory.
+func (r *SensorRepo) Aggregate(ctx context.Context, q entities.SensorAggregateQuery) ([]entities.SensorAggregate, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	filter := newQueryFilter(q)
+	b := buckets{}
+
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	for _, s := range r.Store.readings {
+		if !filter.matchRow(s.DeviceID, s.Timestamp) {
+			continue
+		}
+		bucket := bucketStart(s.Timestamp, q.Bucket)
+		for metric, v := range payloadMetrics(s.Payload) {
+			if filter.matchMetric(metric) {
+				b.add(rollupKey{s.DeviceID, bucket, metric}, v, v, v, v, s.Timestamp, 1)
+			}
+		}
+	}
+	return b.aggregates(), nil
+}
+
+// find returns matching readings ordered by timestamp.
+func (r *SensorRepo) find(ctx context.Context, match func(entities.SensorReading) bool) ([]entities.SensorReading, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	readings := []entities.SensorReading{}
+	for _, s := range r.Store.readings {
+		if match(s) {
+			readings = append(readings, s)
+		}
+	}
+	sort.SliceStable(readings, func(i, j int) bool { return readings[i].Timestamp.Before(readings[j].Timestamp) })
+	return readings, nil
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+// Store holds every table of the in-memory storage driver. It is used for local development
+// and tests where no database is available; all data is lost when the process exits.
+type Store struct {
+	mu       sync.RWMutex
+	devices  map[uuid.UUID]entities.Device
+	users    map[uuid.UUID]entities.User
+	readings []entities.SensorReading
+	alerts   []entities.Alert
+	images   map[uuid.UUID]entities.Image
+	evidence map[uuid.UUID]map[uuid.UUID]bool // alert ID -> linked image IDs
+	cameras  map[uuid.UUID]entities.CameraSettings
+	rollups  map[string]map[rollupKey]entities.SensorRollup
+}
+
+type rollupKey struct {
+	DeviceID uuid.UUID
+	Bucket   time.Time
+	Metric   string
+}
+
+func NewStore() *Store {
+	return &Store{
+		devices:  make(map[uuid.UUID]entities.Device),
+		users:    make(map[uuid.UUID]entities.User),
+		images:   make(map[uuid.UUID]entities.Image),
+		evidence: make(map[uuid.UUID]map[uuid.UUID]bool),
+		cameras:  make(map[uuid.UUID]entities.CameraSettings),
+		rollups: map[string]map[rollupKey]entities.SensorRollup{
+			entities.RollupMinute: {},
+			entities.RollupHour:   {},
+		},
+	}
+}
+
+// checkContext mirrors the database driver's behaviour for requests that were already cancelled.
+func checkContext(ctx context.Context) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return fmt.Errorf("%w: %v", interfaces.ErrQueryTimeout, ctx.Err())
+	default:
+		return fmt.Errorf("%w: %v", interfaces.ErrQueryCanceled, ctx.Err())
+	}
+}
+
+// stamp fills in the ID and CreatedAt the way the database defaults would.
+func stamp(id *uuid.UUID, createdAt *time.Time) {
+	if *id == uuid.Nil {
+		*id = uuid.New()
+	}
+	if createdAt.IsZero() {
+		*createdAt = time.Now()
+	}
+}
@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+type UserRepo struct {
+	Store *Store
+}
+
+func NewUserRepo(store *Store) interfaces.UserRepository {
+	return &UserRepo{Store: store}
+}
+
+func (r *UserRepo) Create(ctx context.Context, user *entities.User) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	// Usernames are unique, as in the database schema
+	for _, u := range r.Store.users {
+		if u.Username == user.Username {
+			return interfaces.ErrDuplicate
+		}
+	}
+	stamp(&user.ID, &user.CreatedAt)
+	if _, exists := r.Store.users[user.ID]; exists {
+		return interfaces.ErrDuplicate
+	}
+	user.UpdatedAt = user.CreatedAt
+	r.Store.users[user.ID] = *user
+	return nil
+}
+
+func (r *UserRepo) CreateFirst(ctx context.Context, user *entities.User) (bool, error) {
+	if err := checkContext(ctx); err != nil {
+		return false, err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	if len(r.Store.users) > 0 {
+		return false, nil
+	}
+	stamp(&user.ID, &user.CreatedAt)
+	user.UpdatedAt = user.CreatedAt
+	r.Store.users[user.ID] = *user
+	return true, nil
+}
+
+func (r *UserRepo) FindByID(ctx context.Context, id uuid.UUID) (*entities.User, error) {
+	if err := checkContext(ctx); err != nil {
+		return &entities.User{}, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	user, ok := r.Store.users[id]
+	if !ok {
+		return &user, interfaces.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *UserRepo) FindByUsername(ctx context.Context, username string) (*entities.User, error) {
+	if err := checkContext(ctx); err != nil {
+		return &entities.User{}, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	for _, u := range r.Store.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return &entities.User{}, interfaces.ErrNotFound
+}
+
+func (r *UserRepo) FindAll(ctx context.Context) ([]entities.User, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	users := make([]entities.User, 0, len(r.Store.users))
+	for _, u := range r.Store.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+func (r *UserRepo) Count(ctx context.Context) (int64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+	return int64(len(r.Store.users)), nil
+}
+
+func (r *UserRepo) Update(ctx context.Context, user *entities.User) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	return r.update(user)
+}
+
+// update saves the user. The caller holds Store.mu.
+func (r *UserRepo) update(user *entities.User) error {
+	for id, u := range r.Store.users {
+		if u.Username == user.Username && id != user.ID {
+			return interfaces.ErrDuplicate
+		}
+	}
+	stamp(&user.ID, &user.CreatedAt)
+	user.UpdatedAt = time.Now()
+	r.Store.users[user.ID] = *user
+	return nil
+}
+
+func (r *UserRepo) UpdateKeepingAdmin(ctx context.Context, user *entities.User) (bool, error) {
+	if err := checkContext(ctx); err != nil {
+		return false, err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	if ok, err := r.keepsAdmin(user.ID, user.Role != entities.RoleAdmin || user.Disabled); !ok || err != nil {
+		return false, err
+	}
+	return true, r.update(user)
+}
+
+func (r *UserRepo) DeleteKeepingAdmin(ctx context.Context, id uuid.UUID) (bool, error) {
+	if err := checkContext(ctx); err != nil {
+		return false, err
+	}
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+	if ok, err := r.keepsAdmin(id, true); !ok || err != nil {
+		return false, err
+	}
+	for deviceID, device := range r.Store.devices {
+		if device.SupervisorID != nil && *device.SupervisorID == id {
+			device.SupervisorID = nil
+			r.Store.devices[deviceID] = device
+		}
+		if device.WorkerID != nil && *device.WorkerID == id {
+			device.WorkerID = nil
+			r.Store.devices[deviceID] = device
+		}
+	}
+	delete(r.Store.users, id)
+	return true, nil
+}
+
+// keepsAdmin reports whether the user may change, i.e. it is not the last enabled Admin or the
+// change keeps it one (leaves is false). The caller holds Store.mu.
+func (r *UserRepo) keepsAdmin(id uuid.UUID, leaves bool) (bool, error) {
+	stored, ok := r.Store.users[id]
+	if !ok {
+		return false, interfaces.ErrNotFound
+	}
+	if !leaves || stored.Role != entities.RoleAdmin || stored.Disabled {
+		return true, nil
+	}
+	for otherID, u := range r.Store.users {
+		if otherID != id && u.Role == entities.RoleAdmin && !u.Disabled {
+			return true, nil
+		}
+	}
+	return false, nil
+}
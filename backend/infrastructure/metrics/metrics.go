@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Minimal Prometheus-compatible metrics registry.
+// We only need a handful of counters and gauges, so a full client library is not worth the dependency.
+
+type metric interface {
+	name() string
+	write(sb *strings.Builder)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]metric{}
+)
+
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[m.name()] = m
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	metricName string
+	help       string
+	value      atomic.Int64
+}
+
+func NewCounter(name, help string) *Counter {
+	c := &Counter{metricName: name, help: help}
+	register(c)
+	return c
+}
+
+func (c *Counter) Inc()         { c.value.Add(1) }
+func (c *Counter) Add(n int64)  { c.value.Add(n) }
+func (c *Counter) Value() int64 { return c.value.Load() }
+func (c *Counter) name() string { return c.metricName }
+
+func (c *Counter) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.metricName, c.help, c.metricName, c.metricName, c.Value())
+}
+
+// CounterVec is a counter partitioned by a single label (e.g. device_id).
+type CounterVec struct {
+	metricName string
+	help       string
+	label      string
+	mu         sync.RWMutex
+	values     map[string]*atomic.Int64
+}
+
+func NewCounterVec(name, help, label string) *CounterVec {
+	v := &CounterVec{metricName: name, help: help, label: label, values: make(map[string]*atomic.Int64)}
+	register(v)
+	return v
+}
+
+func (v *CounterVec) Inc(labelValue string) { v.Add(labelValue, 1) }
+
+func (v *CounterVec) Add(labelValue string, n int64) {
+	v.mu.RLock()
+	c, ok := v.values[labelValue]
+	v.mu.RUnlock()
+	if !ok {
+		v.mu.Lock()
+		if c, ok = v.values[labelValue]; !ok {
+			c = &atomic.Int64{}
+			v.values[labelValue] = c
+		}
+		v.mu.Unlock()
+	}
+	c.Add(n)
+}
+
+func (v *CounterVec) Value(labelValue string) int64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if c, ok := v.values[labelValue]; ok {
+		return c.Load()
+	}
+	return 0
+}
+
+func (v *CounterVec) name() string { return v.metricName }
+
+func (v *CounterVec) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s counter\n", v.metricName, v.help, v.metricName)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(sb, "%s{%s=%q} %d\n", v.metricName, v.label, k, v.values[k].Load())
+	}
+}
+
+// GaugeFunc reports a value computed at scrape time (e.g. a queue length).
+type GaugeFunc struct {
+	metricName string
+	help       string
+	kind       string
+	fn         func() float64
+}
+
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{metricName: name, help: help, kind: "gauge", fn: fn}
+	register(g)
+	return g
+}
+
+// NewCounterFunc reports a counter owned by another component (e.g. an atomic inside a use case).
+func NewCounterFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{metricName: name, help: help, kind: "counter", fn: fn}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) name() string { return g.metricName }
+
+func (g *GaugeFunc) write(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", g.metricName, g.help, g.metricName, g.kind, g.metricName, g.fn())
+}
+
+// Handler serves all registered metrics in the Prometheus text exposition format.
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		registryMu.Lock()
+		names := make([]string, 0, len(registry))
+		for n := range registry {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		var sb strings.Builder
+		for _, n := range names {
+			registry[n].write(&sb)
+		}
+		registryMu.Unlock()
+
+		c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(sb.String()))
+	}
+}
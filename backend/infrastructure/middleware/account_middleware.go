@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AccountLookup returns the current role of a user and whether the account may still act.
+type AccountLookup func(ctx context.Context, userID uuid.UUID) (role string, active bool, err error)
+
+// ActiveAccountMiddleware runs after AuthMiddleware. It rejects tokens of disabled or deleted
+// users and replaces the role claim with the user's current role, so Admin changes apply to
+// tokens already issued.
+func ActiveAccountMiddleware(lookup AccountLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		role, active, err := lookup(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify account"})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is disabled or no longer exists"})
+			c.Abort()
+			return
+		}
+
+		c.Set("role", role)
+		c.Next()
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/infrastructure/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const testSecret = "test-secret"
+
+type account struct {
+	role   string
+	active bool
+}
+
+// testRouter serves /admin to Admins and /images/:id through SignedURLMiddleware, with
+// accounts looked up in the given map. Unknown users fail the lookup.
+func testRouter(accounts map[uuid.UUID]account) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	lookup := func(ctx context.Context, userID uuid.UUID) (string, bool, error) {
+		a, ok := accounts[userID]
+		if !ok {
+			return "", false, errors.New("database unavailable")
+		}
+		return a.role, a.active, nil
+	}
+	activeAccount := ActiveAccountMiddleware(lookup)
+	ok := func(c *gin.Context) { c.String(http.StatusOK, c.GetString("role")) }
+
+	r := gin.New()
+	r.GET("/admin", AuthMiddleware(testSecret), activeAccount, RoleMiddleware(entities.RoleAdmin), ok)
+	r.GET("/images/:id", SignedURLMiddleware(testSecret, activeAccount), ok)
+	return r
+}
+
+func get(t *testing.T, r *gin.Engine, target, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestActiveAccountMiddleware(t *testing.T) {
+	admin, demoted, disabled, unknown := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	r := testRouter(map[uuid.UUID]account{
+		admin:    {entities.RoleAdmin, true},
+		demoted:  {entities.RoleWorker, true},
+		disabled: {entities.RoleAdmin, false},
+	})
+
+	// Every token claims Admin; the account decides
+	cases := []struct {
+		name   string
+		userID uuid.UUID
+		want   int
+	}{
+		{"active admin", admin, http.StatusOK},
+		{"demoted admin", demoted, http.StatusForbidden},
+		{"disabled account", disabled, http.StatusUnauthorized},
+		{"failed lookup", unknown, http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		token, err := utils.GenerateToken(c.userID, entities.RoleAdmin, testSecret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w := get(t, r, "/admin", token); w.Code != c.want {
+			t.Errorf("%s: status %d, want %d", c.name, w.Code, c.want)
+		}
+	}
+
+	if w := get(t, r, "/admin", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("no token: status %d, want 401", w.Code)
+	}
+}
+
+func TestSignedURLMiddlewareChecksAccountOnTokens(t *testing.T) {
+	disabled := uuid.New()
+	r := testRouter(map[uuid.UUID]account{disabled: {entities.RoleSupervisor, false}})
+
+	token, _ := utils.GenerateToken(disabled, entities.RoleSupervisor, testSecret)
+	if w := get(t, r, "/images/1", token); w.Code != http.StatusUnauthorized {
+		t.Errorf("disabled user's token: status %d, want 401", w.Code)
+	}
+
+	// A signed URL stands on its own
+	signed := utils.SignPath("/images/1", testSecret, time.Now().Add(time.Minute))
+	if w := get(t, r, signed, ""); w.Code != http.StatusOK {
+		t.Errorf("signed URL: status %d, want 200", w.Code)
+	}
+	if w := get(t, r, signed+"0", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("tampered signed URL: status %d, want 401", w.Code)
+	}
+}
@@ -1,42 +1,68 @@
 package middleware
 
 import (
-	"fmt"
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
+	"minesense-backend/infrastructure/utils"
+
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 func AuthMiddleware(secret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
-			return
+		if authenticate(c, secret) {
+			c.Next()
 		}
+	}
+}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(secret), nil
-		})
+// authenticate verifies the JWT in the Authorization header and stores its claims in the
+// context, or responds 401 and aborts.
+func authenticate(c *gin.Context, secret string) bool {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		c.Abort()
+		return false
+	}
+
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	claims, err := utils.ParseToken(tokenString, secret)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return false
+	}
+
+	c.Set("user_id", claims["user_id"])
+	c.Set("role", claims["role"])
+	return true
+}
 
-		if err != nil || !token.Valid {
+// QueryTokenAuthMiddleware is AuthMiddleware that also accepts the JWT as ?token=, for clients
+// such as <img> tags that cannot set headers.
+func QueryTokenAuthMiddleware(secret string) gin.HandlerFunc {
+	auth := AuthMiddleware(secret)
+	return func(c *gin.Context) {
+		if token := c.Query("token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		auth(c)
+	}
+}
+
+// StaticTokenMiddleware admits requests bearing a fixed secret, for machine clients such as
+// metrics scrapers that cannot log in.
+func StaticTokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
-
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Set("user_id", claims["user_id"])
-			c.Set("role", claims["role"])
-		}
-
 		c.Next()
 	}
 }
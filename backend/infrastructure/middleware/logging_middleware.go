@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"fmt"
 	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -25,3 +28,44 @@ func LoggingMiddleware() gin.HandlerFunc {
 		)
 	}
 }
+
+// redactedParams are query parameters carrying credentials, for clients that cannot set headers.
+var redactedParams = []string{"token"}
+
+// AccessLogger is gin's request logger with credentials in query strings redacted.
+func AccessLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(p gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			p.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			p.StatusCode,
+			p.Latency,
+			p.ClientIP,
+			p.Method,
+			RedactQuery(p.Path),
+			p.ErrorMessage,
+		)
+	})
+}
+
+// RedactQuery replaces the values of credential query parameters in a request path.
+func RedactQuery(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base + "?REDACTED"
+	}
+	redacted := false
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return base + "?" + query.Encode()
+}
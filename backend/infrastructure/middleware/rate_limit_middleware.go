@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"minesense-backend/infrastructure/metrics"
+	"minesense-backend/infrastructure/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+var rateLimitedCredentials = metrics.NewCounterVec(
+	"minesense_rate_limited_requests_total",
+	"Requests rejected by the per-credential rate limit.",
+	"credential",
+)
+
+// RateLimitMiddleware throttles requests per authenticated credential (the user_id claim set by AuthMiddleware).
+// It must run after AuthMiddleware; unauthenticated requests are keyed by client IP.
+func RateLimitMiddleware(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.ClientIP()
+		if userID, exists := c.Get("user_id"); exists {
+			key = fmt.Sprint(userID)
+		}
+
+		if ok, wait := limiter.Allow(key); !ok {
+			rateLimitedCredentials.Inc(key)
+			c.Header("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(wait)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"minesense-backend/infrastructure/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SignedURLMiddleware admits requests carrying a valid URL signature (see utils.SignPath),
+// as used by <img> tags that cannot send headers, and otherwise requires a JWT like AuthMiddleware
+// followed by account, the ActiveAccountMiddleware of the protected routes.
+func SignedURLMiddleware(secret string, account gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("sig") != "" && utils.VerifyPath(c.Request.URL.Path, c.Request.URL.Query(), secret) == nil {
+			c.Next()
+			return
+		}
+		if authenticate(c, secret) {
+			account(c)
+		}
+	}
+}
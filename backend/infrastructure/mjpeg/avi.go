@@ -0,0 +1,170 @@
+package mjpeg
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// AVI chunk flags.
+const (
+	avifHasIndex  = 0x10
+	aviifKeyframe = 0x10
+)
+
+// aviHeaderSize is the size of everything before the first frame: the RIFF header, the
+// hdrl list (avih, strl with strh and strf) and the movi list header.
+const aviHeaderSize = 12 + 12 + 64 + 12 + 64 + 48 + 12
+
+// AVIWriter writes Motion-JPEG frames into an AVI file, which common players open directly.
+// Sizes and counts in the header are only known at the end, so Close rewrites the header;
+// hence an io.WriteSeeker.
+type AVIWriter struct {
+	w             io.WriteSeeker
+	width, height int
+	fps           int
+
+	movi    int64 // Bytes of frame chunks written after the header
+	idx     int64 // Bytes of the index, once written by Close
+	maxSize int
+	index   []aviIndexEntry
+}
+
+type aviIndexEntry struct {
+	offset uint32 // From the "movi" fourcc
+	size   uint32
+}
+
+// NewAVIWriter writes a placeholder header; frames follow with WriteFrame.
+func NewAVIWriter(w io.WriteSeeker, width, height, fps int) (*AVIWriter, error) {
+	a := &AVIWriter{w: w, width: width, height: height, fps: max(fps, 1)}
+	if err := a.writeHeader(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *AVIWriter) WriteFrame(jpeg []byte) error {
+	chunk := make([]byte, 8, 8+len(jpeg)+1)
+	copy(chunk, "00dc")
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(jpeg)))
+	chunk = append(chunk, jpeg...)
+	if len(jpeg)%2 == 1 {
+		chunk = append(chunk, 0) // Chunks are word-aligned
+	}
+	if _, err := a.w.Write(chunk); err != nil {
+		return err
+	}
+	a.index = append(a.index, aviIndexEntry{offset: uint32(4 + a.movi), size: uint32(len(jpeg))})
+	a.movi += int64(len(chunk))
+	a.maxSize = max(a.maxSize, len(jpeg))
+	return nil
+}
+
+// Close appends the frame index and rewrites the header with the final sizes.
+func (a *AVIWriter) Close() error {
+	idx := make([]byte, 8+16*len(a.index))
+	copy(idx, "idx1")
+	binary.LittleEndian.PutUint32(idx[4:], uint32(16*len(a.index)))
+	for i, e := range a.index {
+		entry := idx[8+16*i:]
+		copy(entry, "00dc")
+		binary.LittleEndian.PutUint32(entry[4:], aviifKeyframe)
+		binary.LittleEndian.PutUint32(entry[8:], e.offset)
+		binary.LittleEndian.PutUint32(entry[12:], e.size)
+	}
+	if _, err := a.w.Write(idx); err != nil {
+		return err
+	}
+	a.idx = int64(len(idx))
+
+	if _, err := a.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := a.writeHeader(); err != nil {
+		return err
+	}
+	_, err := a.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+func (a *AVIWriter) writeHeader() error {
+	var h []byte
+	u32 := func(v uint32) { h = binary.LittleEndian.AppendUint32(h, v) }
+	u16 := func(v uint16) { h = binary.LittleEndian.AppendUint16(h, v) }
+	fourcc := func(s string) { h = append(h, s...) }
+
+	frames := uint32(len(a.index))
+
+	fourcc("RIFF")
+	u32(uint32(aviHeaderSize - 8 + a.movi + a.idx))
+	fourcc("AVI ")
+
+	fourcc("LIST")
+	u32(4 + 64 + 12 + 64 + 48)
+	fourcc("hdrl")
+
+	// Main header
+	fourcc("avih")
+	u32(56)
+	u32(uint32(1000000 / a.fps)) // Microseconds per frame
+	u32(uint32(a.maxSize * a.fps))
+	u32(0)
+	u32(avifHasIndex)
+	u32(frames)
+	u32(0)
+	u32(1) // Streams
+	u32(uint32(a.maxSize))
+	u32(uint32(a.width))
+	u32(uint32(a.height))
+	u32(0)
+	u32(0)
+	u32(0)
+	u32(0)
+
+	fourcc("LIST")
+	u32(4 + 64 + 48)
+	fourcc("strl")
+
+	// Stream header
+	fourcc("strh")
+	u32(56)
+	fourcc("vids")
+	fourcc("MJPG")
+	u32(0) // Flags
+	u16(0) // Priority
+	u16(0) // Language
+	u32(0) // Initial frames
+	u32(1) // Scale
+	u32(uint32(a.fps))
+	u32(0) // Start
+	u32(frames)
+	u32(uint32(a.maxSize))
+	u32(0xFFFFFFFF) // Default quality
+	u32(0)          // Sample size (varies)
+	u16(0)
+	u16(0)
+	u16(uint16(a.width))
+	u16(uint16(a.height))
+
+	// Stream format (BITMAPINFOHEADER)
+	fourcc("strf")
+	u32(40)
+	u32(40)
+	u32(uint32(a.width))
+	u32(uint32(a.height))
+	u16(1)  // Planes
+	u16(24) // Bits per pixel
+	fourcc("MJPG")
+	u32(uint32(a.width * a.height * 3))
+	u32(0)
+	u32(0)
+	u32(0)
+	u32(0)
+
+	fourcc("LIST")
+	u32(uint32(4 + a.movi))
+	fourcc("movi")
+
+	_, err := a.w.Write(h)
+	return err
+}
@@ -0,0 +1,102 @@
+package mjpeg
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// Broker fans camera frames out to live viewers in memory. Each camera's upload feeds any
+// number of viewers, and the latest frame is kept so a new viewer sees a picture at once.
+// A viewer holds at most one pending frame: one that falls behind skips to the newest frame
+// instead of slowing down the upload or the other viewers.
+type Broker struct {
+	mu    sync.Mutex
+	feeds map[uuid.UUID]*feed
+
+	viewers atomic.Int64
+	skipped atomic.Int64
+}
+
+type feed struct {
+	latest  []byte
+	viewers map[*Viewer]struct{}
+}
+
+// Viewer receives the frames of one camera.
+type Viewer struct {
+	deviceID uuid.UUID
+	frames   chan []byte
+}
+
+// Frames delivers frames as they arrive; only the newest pending frame is kept.
+func (v *Viewer) Frames() <-chan []byte { return v.frames }
+
+func NewBroker() *Broker {
+	return &Broker{feeds: make(map[uuid.UUID]*feed)}
+}
+
+func (b *Broker) feed(deviceID uuid.UUID) *feed {
+	f, ok := b.feeds[deviceID]
+	if !ok {
+		f = &feed{viewers: make(map[*Viewer]struct{})}
+		b.feeds[deviceID] = f
+	}
+	return f
+}
+
+// PublishFrame hands a frame to every viewer of the camera. It never blocks; frames must
+// not be modified afterwards since viewers share them.
+func (b *Broker) PublishFrame(deviceID uuid.UUID, frame []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	f := b.feed(deviceID)
+	f.latest = frame
+	for v := range f.viewers {
+		select {
+		case v.frames <- frame:
+			continue
+		default:
+		}
+		// Replace the frame the viewer has not picked up yet
+		select {
+		case <-v.frames:
+			b.skipped.Add(1)
+		default:
+		}
+		select {
+		case v.frames <- frame:
+		default:
+		}
+	}
+}
+
+// Subscribe registers a viewer of the camera and returns the latest frame, if any.
+// Callers must Unsubscribe when the viewer goes away.
+func (b *Broker) Subscribe(deviceID uuid.UUID) (*Viewer, []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v := &Viewer{deviceID: deviceID, frames: make(chan []byte, 1)}
+	f := b.feed(deviceID)
+	f.viewers[v] = struct{}{}
+	b.viewers.Add(1)
+	return v, f.latest
+}
+
+func (b *Broker) Unsubscribe(v *Viewer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if f, ok := b.feeds[v.deviceID]; ok {
+		if _, ok := f.viewers[v]; ok {
+			delete(f.viewers, v)
+			b.viewers.Add(-1)
+		}
+	}
+}
+
+// Viewers is the number of connected viewers across all cameras.
+func (b *Broker) Viewers() int { return int(b.viewers.Load()) }
+
+// Skipped counts frames a viewer never saw because it fell behind.
+func (b *Broker) Skipped() int64 { return b.skipped.Load() }
@@ -0,0 +1,14 @@
+package mjpeg
+
+import (
+	"io"
+
+	"minesense-backend/domain/interfaces"
+)
+
+// AVIEncoder implements interfaces.ClipEncoder with AVIWriter.
+type AVIEncoder struct{}
+
+func (AVIEncoder) NewClip(w io.WriteSeeker, width, height, fps int) (interfaces.ClipWriter, error) {
+	return NewAVIWriter(w, width, height, fps)
+}
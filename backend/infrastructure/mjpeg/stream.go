@@ -0,0 +1,62 @@
+package mjpeg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// boundary separates the parts of the multipart/x-mixed-replace response.
+	boundary = "minesenseframe"
+	// writeWait bounds writing one frame to a viewer.
+	writeWait = 10 * time.Second
+)
+
+// ContentType is the media type of an MJPEG stream.
+const ContentType = "multipart/x-mixed-replace; boundary=" + boundary
+
+// Stream writes first (if any) and then every frame the viewer receives as an MJPEG stream,
+// until ctx is done or a write fails. Browsers render it directly in an <img> tag.
+func Stream(ctx context.Context, w http.ResponseWriter, v *Viewer, first []byte) error {
+	rc := http.NewResponseController(w)
+	header := w.Header()
+	header.Set("Content-Type", ContentType)
+	header.Set("Cache-Control", "no-cache, no-store")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+	w.WriteHeader(http.StatusOK)
+
+	write := func(frame []byte) error {
+		rc.SetWriteDeadline(time.Now().Add(writeWait))
+		if _, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", boundary, len(frame)); err != nil {
+			return err
+		}
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte("\r\n")); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if first != nil {
+		if err := write(first); err != nil {
+			return err
+		}
+	} else if err := rc.Flush(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case frame := <-v.Frames():
+			if err := write(frame); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
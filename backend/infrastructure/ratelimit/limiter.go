@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// idleEviction is how long an untouched bucket is kept before being swept.
+const idleEviction = 10 * time.Minute
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Limiter is a keyed token-bucket rate limiter.
+// Each key (device ID, credential, ...) gets its own bucket refilled at Rate tokens per second up to Burst.
+type Limiter struct {
+	Rate  float64
+	Burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		Rate:      rate,
+		Burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes one token for key. When the bucket is empty it returns false
+// and how long the caller should wait before the next token is available.
+// A limiter with a non-positive rate allows everything.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil || l.Rate <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.Burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	// Refill based on time elapsed since the last request
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens = math.Min(l.Burst, b.tokens+elapsed*l.Rate)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.Rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that have been idle long enough to be full again.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleEviction {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleEviction {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// RetryAfterSeconds rounds a wait up to whole seconds for the Retry-After header.
+func RetryAfterSeconds(wait time.Duration) int {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	return secs
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllowSpendsBurstThenWaits(t *testing.T) {
+	l := NewLimiter(0.5, 3)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("cam"); !ok {
+			t.Fatalf("request %d refused within the burst", i+1)
+		}
+	}
+	ok, wait := l.Allow("cam")
+	if ok {
+		t.Fatal("request beyond the burst allowed")
+	}
+	// One token at 0.5/s takes about two seconds
+	if wait < 1900*time.Millisecond || wait > 2*time.Second {
+		t.Fatalf("wait = %v, want about 2s", wait)
+	}
+	if RetryAfterSeconds(wait) != 2 {
+		t.Fatalf("RetryAfterSeconds(%v) = %d, want 2", wait, RetryAfterSeconds(wait))
+	}
+	if RetryAfterSeconds(0) != 1 {
+		t.Fatal("Retry-After must be at least one second")
+	}
+
+	// Keys have their own buckets
+	if ok, _ := l.Allow("other"); !ok {
+		t.Fatal("a fresh key was refused")
+	}
+}
+
+func TestAllowRefills(t *testing.T) {
+	l := NewLimiter(1, 1)
+	l.Allow("cam")
+	if ok, _ := l.Allow("cam"); ok {
+		t.Fatal("empty bucket allowed a request")
+	}
+
+	// Pretend the last request was two seconds ago: the bucket refills, but never beyond Burst
+	l.buckets["cam"].lastSeen = time.Now().Add(-2 * time.Second)
+	if ok, _ := l.Allow("cam"); !ok {
+		t.Fatal("refilled bucket refused a request")
+	}
+	if ok, _ := l.Allow("cam"); ok {
+		t.Fatal("bucket refilled beyond its burst")
+	}
+}
+
+func TestDisabledLimiterAllowsEverything(t *testing.T) {
+	var nilLimiter *Limiter
+	for _, l := range []*Limiter{nilLimiter, NewLimiter(0, 1)} {
+		for i := 0; i < 10; i++ {
+			if ok, wait := l.Allow("cam"); !ok || wait != 0 {
+				t.Fatalf("disabled limiter refused a request")
+			}
+		}
+	}
+}
+
+func TestSweepEvictsIdleBuckets(t *testing.T) {
+	l := NewLimiter(1, 1)
+	l.Allow("idle")
+	l.Allow("busy")
+
+	now := time.Now()
+	l.buckets["idle"].lastSeen = now.Add(-2 * idleEviction)
+	l.lastSweep = now.Add(-2 * idleEviction)
+	l.Allow("busy")
+
+	if _, ok := l.buckets["idle"]; ok {
+		t.Fatal("idle bucket was not swept")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Fatal("active bucket was swept")
+	}
+}
@@ -0,0 +1,175 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/uuid"
+)
+
+// Supported telemetry encodings, selected by the request Content-Type.
+const (
+	FormatJSON     = "application/json"
+	FormatCBOR     = "application/cbor"
+	FormatProtobuf = "application/x-protobuf"
+)
+
+var ErrInvalidDeviceID = errors.New("invalid device ID format")
+
+// maxClockAhead is how far ahead of the server a device's clock is trusted.
+const maxClockAhead = time.Minute
+
+// Reading is a decoded telemetry upload, independent of its wire format.
+type Reading struct {
+	DeviceID   uuid.UUID // uuid.Nil when the body did not carry one
+	SensorType string
+	Payload    json.RawMessage
+	// Envelope is true when the body used the {device_id, sensor_type, payload} form,
+	// false for flat telemetry where the whole body is the payload.
+	Envelope bool
+	// CapturedAt is the device-side capture time, zero when the body did not carry one.
+	CapturedAt time.Time
+}
+
+// Time returns when the reading was taken: its capture time when that is at most maxClockAhead
+// ahead of now and maxAge behind it, otherwise now (e.g. for a node sending time since boot).
+func (r *Reading) Time(now time.Time, maxAge time.Duration) time.Time {
+	if r.CapturedAt.IsZero() || r.CapturedAt.After(now.Add(maxClockAhead)) || r.CapturedAt.Before(now.Add(-maxAge)) {
+		return now
+	}
+	return r.CapturedAt
+}
+
+// CommandState is the reply sent to a device after an upload.
+type CommandState struct {
+	Buzzer bool `json:"buzzer" cbor:"buzzer"`
+}
+
+type envelope struct {
+	DeviceID   string          `json:"device_id"`
+	SensorType string          `json:"sensor_type"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+var mapStringAnyType = reflect.TypeOf(map[string]interface{}(nil))
+
+var cborDecMode, _ = cbor.DecOptions{
+	DefaultMapType: mapStringAnyType,
+}.DecMode()
+
+// FormatFromContentType maps a Content-Type header to a supported format, defaulting to JSON.
+func FormatFromContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return FormatJSON
+	}
+	switch mediaType {
+	case FormatCBOR:
+		return FormatCBOR
+	case FormatProtobuf, "application/protobuf", "application/vnd.google.protobuf":
+		return FormatProtobuf
+	default:
+		return FormatJSON
+	}
+}
+
+// Decode parses a telemetry body in the given format into a Reading.
+func Decode(format string, body []byte) (*Reading, error) {
+	switch format {
+	case FormatCBOR:
+		return decodeCBOR(body)
+	case FormatProtobuf:
+		return decodeProtobuf(body)
+	default:
+		return decodeJSON(body)
+	}
+}
+
+// EncodeCommandState serialises the device reply in the given format.
+func EncodeCommandState(format string, state CommandState) ([]byte, error) {
+	switch format {
+	case FormatCBOR:
+		return cbor.Marshal(state)
+	case FormatProtobuf:
+		return marshalCommandState(state), nil
+	default:
+		return json.Marshal(state)
+	}
+}
+
+func decodeJSON(body []byte) (*Reading, error) {
+	// 1. Standard format: {device_id, sensor_type, payload}
+	var input envelope
+	if err := json.Unmarshal(body, &input); err == nil && input.DeviceID != "" && input.SensorType != "" {
+		id, err := uuid.Parse(input.DeviceID)
+		if err != nil {
+			return nil, ErrInvalidDeviceID
+		}
+		return &Reading{DeviceID: id, SensorType: input.SensorType, Payload: input.Payload, Envelope: true}, nil
+	}
+
+	// 2. Fallback: Flat JSON (Telemetry), the whole body is the payload
+	var flatMap map[string]interface{}
+	if err := json.Unmarshal(body, &flatMap); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	reading := &Reading{SensorType: "telemetry", Payload: body}
+	if idStr, ok := flatMap["device_id"].(string); ok {
+		reading.DeviceID, _ = uuid.Parse(idStr)
+	}
+	return reading, nil
+}
+
+// decodeCBOR accepts the same shapes as JSON and re-encodes the payload as JSON for storage.
+func decodeCBOR(body []byte) (*Reading, error) {
+	var doc map[string]interface{}
+	if err := cborDecMode.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("invalid CBOR body: %w", err)
+	}
+	asJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("CBOR body cannot be represented as JSON: %w", err)
+	}
+	return decodeJSON(asJSON)
+}
+
+func decodeProtobuf(body []byte) (*Reading, error) {
+	msg, err := unmarshalTelemetry(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf body: %w", err)
+	}
+
+	reading := &Reading{SensorType: msg.SensorType}
+	if reading.SensorType == "" {
+		reading.SensorType = "telemetry"
+	}
+	if msg.DeviceID != "" {
+		id, err := uuid.Parse(msg.DeviceID)
+		if err != nil {
+			return nil, ErrInvalidDeviceID
+		}
+		reading.DeviceID = id
+		reading.Envelope = msg.SensorType != ""
+	}
+
+	payload := make(map[string]interface{}, len(msg.Metrics)+len(msg.Flags)+1)
+	for k, v := range msg.Metrics {
+		payload[k] = v
+	}
+	for k, v := range msg.Flags {
+		payload[k] = v
+	}
+	if msg.TimestampMs > 0 {
+		reading.CapturedAt = time.UnixMilli(msg.TimestampMs).UTC()
+	}
+	reading.Payload, err = json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return reading, nil
+}
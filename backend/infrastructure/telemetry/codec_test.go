@@ -0,0 +1,158 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func decodePayload(t *testing.T, reading *Reading) map[string]interface{} {
+	t.Helper()
+	var payload map[string]interface{}
+	if err := json.Unmarshal(reading.Payload, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	return payload
+}
+
+func TestDecodeEnvelope(t *testing.T) {
+	id := uuid.New()
+	doc := map[string]interface{}{
+		"device_id":   id.String(),
+		"sensor_type": "helmet",
+		"payload":     map[string]interface{}{"gas": 412.5, "fall": true},
+	}
+	jsonBody, _ := json.Marshal(doc)
+	cborBody, _ := cbor.Marshal(doc)
+
+	for format, body := range map[string][]byte{FormatJSON: jsonBody, FormatCBOR: cborBody} {
+		reading, err := Decode(format, body)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if reading.DeviceID != id || reading.SensorType != "helmet" || !reading.Envelope {
+			t.Fatalf("%s: got %+v", format, reading)
+		}
+		payload := decodePayload(t, reading)
+		if payload["gas"] != 412.5 || payload["fall"] != true {
+			t.Fatalf("%s: payload %v", format, payload)
+		}
+	}
+}
+
+func TestDecodeFlatTelemetry(t *testing.T) {
+	reading, err := Decode(FormatJSON, []byte(`{"gas": 12, "temp": 30.5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reading.DeviceID != uuid.Nil || reading.SensorType != "telemetry" || reading.Envelope {
+		t.Fatalf("got %+v", reading)
+	}
+
+	if _, err := Decode(FormatJSON, []byte(`{"device_id": "nope", "sensor_type": "helmet"}`)); err != ErrInvalidDeviceID {
+		t.Fatalf("invalid device ID: got %v", err)
+	}
+}
+
+func appendMapEntry(b []byte, field protowire.Number, key string, value func([]byte) []byte) []byte {
+	var entry []byte
+	entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+	entry = protowire.AppendString(entry, key)
+	entry = value(entry)
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendBytes(b, entry)
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	id := uuid.New()
+	captured := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, id.String())
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, "helmet")
+	b = appendMapEntry(b, 3, "gas", func(e []byte) []byte {
+		e = protowire.AppendTag(e, 2, protowire.Fixed64Type)
+		return protowire.AppendFixed64(e, math.Float64bits(412.5))
+	})
+	b = appendMapEntry(b, 4, "fall", func(e []byte) []byte {
+		e = protowire.AppendTag(e, 2, protowire.VarintType)
+		return protowire.AppendVarint(e, protowire.EncodeBool(true))
+	})
+	b = protowire.AppendTag(b, 5, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(captured.UnixMilli()))
+	// An unknown field is skipped
+	b = protowire.AppendTag(b, 99, protowire.BytesType)
+	b = protowire.AppendString(b, "future")
+
+	reading, err := Decode(FormatProtobuf, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reading.DeviceID != id || reading.SensorType != "helmet" || !reading.Envelope {
+		t.Fatalf("got %+v", reading)
+	}
+	payload := decodePayload(t, reading)
+	if len(payload) != 2 || payload["gas"] != 412.5 || payload["fall"] != true {
+		t.Fatalf("payload %v", payload)
+	}
+	if !reading.CapturedAt.Equal(captured) {
+		t.Fatalf("CapturedAt %v, want %v", reading.CapturedAt, captured)
+	}
+
+	if _, err := Decode(FormatProtobuf, []byte{0x0a, 0x05, 'a'}); err == nil {
+		t.Fatal("truncated message decoded without error")
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name       string
+		capturedAt time.Time
+		want       time.Time
+	}{
+		{"missing", time.Time{}, now},
+		{"buffered", now.Add(-30 * time.Second), now.Add(-30 * time.Second)},
+		{"older than maxAge", now.Add(-time.Hour), now},
+		{"time since boot", time.UnixMilli(90_000), now},
+		{"ahead of the server", now.Add(time.Hour), now},
+	}
+	for _, c := range cases {
+		reading := &Reading{CapturedAt: c.capturedAt}
+		if got := reading.Time(now, time.Minute); !got.Equal(c.want) {
+			t.Errorf("%s: Time = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestEncodeCommandState(t *testing.T) {
+	state := CommandState{Buzzer: true}
+
+	body, err := EncodeCommandState(FormatCBOR, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromCBOR CommandState
+	if err := cbor.Unmarshal(body, &fromCBOR); err != nil || fromCBOR != state {
+		t.Fatalf("CBOR round trip: %+v, %v", fromCBOR, err)
+	}
+
+	body, _ = EncodeCommandState(FormatProtobuf, state)
+	num, typ, n := protowire.ConsumeTag(body)
+	if n < 0 || num != 1 || typ != protowire.VarintType {
+		t.Fatalf("protobuf reply has unexpected tag %d/%d", num, typ)
+	}
+	if v, _ := protowire.ConsumeVarint(body[n:]); !protowire.DecodeBool(v) {
+		t.Fatal("protobuf reply lost the buzzer state")
+	}
+	if body, _ := EncodeCommandState(FormatProtobuf, CommandState{}); len(body) != 0 {
+		t.Fatalf("protobuf reply for the default state is %d bytes, want 0", len(body))
+	}
+}
@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"errors"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Hand-written wire codec for the messages in proto/telemetry.proto.
+// The messages are small and stable, so this avoids a protoc step in the build.
+
+var errMalformed = errors.New("malformed message")
+
+type telemetryMessage struct {
+	DeviceID    string
+	SensorType  string
+	Metrics     map[string]float64
+	Flags       map[string]bool
+	TimestampMs int64
+}
+
+func unmarshalTelemetry(b []byte) (*telemetryMessage, error) {
+	msg := &telemetryMessage{Metrics: map[string]float64{}, Flags: map[string]bool{}}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			msg.DeviceID, b = v, b[n:]
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			msg.SensorType, b = v, b[n:]
+		case (num == 3 || num == 4) && typ == protowire.BytesType:
+			entry, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			if err := consumeMapEntry(entry, num == 3, msg); err != nil {
+				return nil, err
+			}
+		case num == 5 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			msg.TimestampMs, b = int64(v), b[n:]
+		default:
+			// Skip unknown fields for forward compatibility
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return msg, nil
+}
+
+// consumeMapEntry decodes a map<string, double> (metrics) or map<string, bool> (flags) entry.
+func consumeMapEntry(b []byte, isMetric bool, msg *telemetryMessage) error {
+	var key string
+	var num float64
+	var flag bool
+	for len(b) > 0 {
+		fieldNum, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch {
+		case fieldNum == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			key, b = v, b[n:]
+		case fieldNum == 2 && isMetric && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			num, b = math.Float64frombits(v), b[n:]
+		case fieldNum == 2 && !isMetric && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			flag, b = protowire.DecodeBool(v), b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(fieldNum, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	if key == "" {
+		return errMalformed
+	}
+	if isMetric {
+		msg.Metrics[key] = num
+	} else {
+		msg.Flags[key] = flag
+	}
+	return nil
+}
+
+func marshalCommandState(state CommandState) []byte {
+	var b []byte
+	if state.Buzzer {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(true))
+	}
+	return b
+}
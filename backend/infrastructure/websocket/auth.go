@@ -0,0 +1,172 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"minesense-backend/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// authTimeout is how long a connection opened without a token has to send its auth message.
+const authTimeout = 10 * time.Second
+
+// accountCheckInterval is how often connected users' accounts are re-checked, so role changes
+// and disabled or deleted accounts reach open connections.
+const accountCheckInterval = 30 * time.Second
+
+// accountLookupTimeout bounds one account lookup of the periodic re-check.
+const accountLookupTimeout = 5 * time.Second
+
+// ErrAuthUnavailable is returned by Authenticate when the account behind a valid token could
+// not be checked; connections are refused with 503 rather than 401.
+var ErrAuthUnavailable = errors.New("unable to verify account")
+
+// Identity is the authenticated user behind a connection.
+type Identity struct {
+	UserID string
+	Role   string
+}
+
+// DeviceInfo is what the hub needs to know about a device to route its messages.
+type DeviceInfo struct {
+	Zone         string
+	SupervisorID string
+	WorkerID     string
+}
+
+// SetAllowedOrigins restricts which browser origins may open a WebSocket. "*" allows any origin;
+// an empty list only allows same-origin requests. Clients that send no Origin (mobile apps) are allowed.
+func (h *Hub) SetAllowedOrigins(origins []string) {
+	allowed := map[string]bool{}
+	for _, o := range origins {
+		if o = strings.TrimSpace(o); o != "" {
+			allowed[strings.ToLower(strings.TrimSuffix(o, "/"))] = true
+		}
+	}
+	h.upgrader.CheckOrigin = func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || allowed["*"] {
+			return true
+		}
+		if allowed[strings.ToLower(origin)] {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && len(allowed) == 0 && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// requestToken returns a token passed in the Authorization header or the token query parameter.
+func requestToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	return c.Query("token")
+}
+
+// respondAuthError refuses a connection whose token was rejected by Authenticate.
+func respondAuthError(c *gin.Context, err error) {
+	if errors.Is(err, ErrAuthUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify account"})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+}
+
+// awaitAuth reads the first message of a connection opened without a token,
+// which must be {"action": "auth", "token": "..."} and may carry last_seq and protocol.
+func (h *Hub) awaitAuth(conn *websocket.Conn) (Identity, clientRequest, error) {
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return Identity{}, clientRequest{}, errors.New("authentication timed out")
+	}
+	var req clientRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.Action != "auth" || req.Token == "" {
+		return Identity{}, req, errors.New(`first message must be {"action": "auth", "token": "<jwt>"}`)
+	}
+	identity, err := h.Authenticate(context.Background(), req.Token)
+	return identity, req, err
+}
+
+// rejectConn tells the client why it is being disconnected and closes the socket.
+func rejectConn(conn *websocket.Conn, protocol int, reason string) {
+	conn.WriteMessage(websocket.TextMessage, controlMessage(protocol, "error", gin.H{"message": reason}))
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+		time.Now().Add(time.Second))
+	conn.Close()
+}
+
+// allowed reports whether the client's role may see the message, which must be resolved.
+// Supervisors only receive events for devices they supervise and Workers for devices they wear.
+// Callers hold c.mu.
+func (c *Client) allowed(meta *messageMeta) bool {
+	switch c.identity.Role {
+	case entities.RoleAdmin:
+		return true
+	case entities.RoleSupervisor:
+		return meta.DeviceID == "" || meta.Supervisor == c.identity.UserID
+	case entities.RoleWorker:
+		return meta.DeviceID == "" || meta.Worker == c.identity.UserID
+	default:
+		return false
+	}
+}
+
+// accountLoop re-checks the accounts of connected users until the process exits.
+func (h *Hub) accountLoop() {
+	ticker := time.NewTicker(accountCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.checkAccounts()
+	}
+}
+
+// checkAccounts applies each connected user's current role and disconnects users whose account
+// was disabled or deleted. Users whose account cannot be looked up keep their connections.
+func (h *Hub) checkAccounts() {
+	h.mu.Lock()
+	users := map[string][]*Client{}
+	for client := range h.clients {
+		users[client.identity.UserID] = append(users[client.identity.UserID], client)
+	}
+	h.mu.Unlock()
+
+	for userID, clients := range users {
+		ctx, cancel := context.WithTimeout(context.Background(), accountLookupTimeout)
+		role, active, err := h.Account(ctx, userID)
+		cancel()
+		if err != nil {
+			log.Printf("WebSocket: failed to re-check account of user %s: %v", userID, err)
+			continue
+		}
+
+		h.mu.Lock()
+		for _, client := range clients {
+			if _, ok := h.clients[client]; !ok {
+				continue
+			}
+			if !active {
+				h.remove(client)
+				log.Printf("%s: disconnected user %s, account disabled or deleted", client.transport(), userID)
+				continue
+			}
+			client.mu.Lock()
+			client.identity.Role = role
+			client.mu.Unlock()
+		}
+		h.mu.Unlock()
+	}
+}
@@ -0,0 +1,34 @@
+package websocket
+
+import (
+	"testing"
+
+	"minesense-backend/domain/entities"
+)
+
+func TestAllowedScopesByRole(t *testing.T) {
+	const user = "user-1"
+	supervised := &messageMeta{Type: "sensor_update", DeviceID: "dev-1", Supervisor: user, resolved: true}
+	worn := &messageMeta{Type: "sensor_update", DeviceID: "dev-2", Supervisor: "supervisor-2", Worker: user, resolved: true}
+	other := &messageMeta{Type: "sensor_update", DeviceID: "dev-3", Supervisor: "supervisor-2", Worker: "worker-2", resolved: true}
+	unassigned := &messageMeta{Type: "alert", DeviceID: "dev-4", resolved: true}
+	global := &messageMeta{Type: "system", resolved: true}
+
+	cases := []struct {
+		role string
+		want map[*messageMeta]bool
+	}{
+		{entities.RoleAdmin, map[*messageMeta]bool{supervised: true, worn: true, other: true, unassigned: true, global: true}},
+		{entities.RoleSupervisor, map[*messageMeta]bool{supervised: true, worn: false, other: false, unassigned: false, global: true}},
+		{entities.RoleWorker, map[*messageMeta]bool{supervised: false, worn: true, other: false, unassigned: false, global: true}},
+		{"User", map[*messageMeta]bool{supervised: false, worn: false, other: false, unassigned: false, global: false}},
+	}
+	for _, c := range cases {
+		client := &Client{identity: Identity{UserID: user, Role: c.role}}
+		for meta, want := range c.want {
+			if got := client.allowed(meta); got != want {
+				t.Errorf("%s on device %q: allowed = %v, want %v", c.role, meta.DeviceID, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Backend relays broadcast messages between backend instances, so a reading posted to one
+// instance reaches clients connected to any of them. Without a backend the hub is in-process only.
+type Backend interface {
+	// Publish sends an encoded message to the other instances.
+	Publish(ctx context.Context, body []byte) error
+	// Listen delivers messages published by other instances until ctx is done or the connection fails.
+	Listen(ctx context.Context, deliver func(body []byte)) error
+}
+
+const (
+	// publishTimeout bounds a single relay of a message to the backend.
+	publishTimeout = 5 * time.Second
+	// maxListenBackoff caps the wait between attempts to re-establish the listener.
+	maxListenBackoff = 30 * time.Second
+)
+
+// relay queues a locally published message for the other instances without blocking Run.
+func (h *Hub) relay(body []byte) {
+	if h.Backend == nil {
+		return
+	}
+	select {
+	case h.outbound <- body:
+	default:
+		h.relayFailed.Add(1)
+	}
+}
+
+// publishLoop forwards queued messages to the backend.
+func (h *Hub) publishLoop() {
+	for body := range h.outbound {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		err := h.Backend.Publish(ctx, body)
+		cancel()
+		if err != nil {
+			h.relayFailed.Add(1)
+			log.Printf("WebSocket: failed to relay message: %v", err)
+		}
+	}
+}
+
+// listenLoop receives messages from other instances, reconnecting with backoff when the listener fails.
+func (h *Hub) listenLoop() {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := h.Backend.Listen(context.Background(), func(body []byte) {
+			h.relayReceived.Add(1)
+			select {
+			case h.inbound <- body:
+			default:
+				h.broadcastDropped.Add(1)
+			}
+		})
+		if time.Since(start) > maxListenBackoff {
+			backoff = time.Second
+		}
+		log.Printf("WebSocket: broadcast listener stopped: %v; retrying in %s", err, backoff)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxListenBackoff {
+			backoff = maxListenBackoff
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait is the time allowed to write one message to the peer.
+	writeWait = 10 * time.Second
+	// pongWait is how long the peer may stay silent before the connection is considered dead.
+	pongWait = 60 * time.Second
+	// pingPeriod must be shorter than pongWait so a healthy peer always answers in time.
+	pingPeriod = pongWait * 9 / 10
+	// maxRequestSize bounds client control messages (subscribe/unsubscribe).
+	maxRequestSize = 4096
+	// controlBuffer holds replies to the client's own requests.
+	controlBuffer = 8
+)
+
+// Client is one authenticated WebSocket or SSE connection and the topics it subscribed to.
+// Only writePump writes to the connection and only readLoop reads from it.
+type Client struct {
+	hub      *Hub
+	conn     *websocket.Conn
+	sse      *sseStream // set instead of conn for Server-Sent Events
+	identity Identity
+	protocol int
+
+	// send carries broadcast messages; the hub closes it to disconnect the client.
+	send chan []byte
+	// control carries replies to subscribe/unsubscribe requests and is never closed.
+	control chan []byte
+	// evicted is closed by the hub when the client fell too far behind; its backlog is discarded.
+	evicted chan struct{}
+	// registered is closed by Run once the client receives broadcasts and its replay backlog is captured.
+	registered chan struct{}
+
+	mu           sync.Mutex // guards subscription and identity.Role
+	subscription Subscription
+
+	// Resume state: set from last_seq before registration, the rest by Run when the client registers.
+	resume    bool
+	lastSeq   int64
+	floor     int64
+	resumeSeq int64
+	backlog   []replayEntry
+	gap       bool
+}
+
+func newClient(h *Hub, conn *websocket.Conn, identity Identity) *Client {
+	return &Client{
+		hub:          h,
+		conn:         conn,
+		identity:     identity,
+		protocol:     ProtocolV1,
+		send:         make(chan []byte, h.sendBuffer),
+		control:      make(chan []byte, controlBuffer),
+		evicted:      make(chan struct{}),
+		registered:   make(chan struct{}),
+		subscription: Subscription{},
+	}
+}
+
+// wants reports whether the client may see the message and subscribed to it. The hub resolves
+// the device of live messages before fanning them out; others are resolved here.
+func (c *Client) wants(h *Hub, meta *messageMeta) bool {
+	h.resolve(meta)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.allowed(meta) && c.subscription.Matches(*meta)
+}
+
+// reply queues a response to the client's own request, dropping it if the client is not reading.
+func (c *Client) reply(messageType string, fields map[string]interface{}) {
+	select {
+	case c.control <- controlMessage(c.protocol, messageType, fields):
+	default:
+		c.hub.messagesDropped.Add(1)
+	}
+}
+
+// writePump delivers queued messages and keeps the connection alive with pings.
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	if c.resume {
+		if err := c.replay(); err != nil {
+			logWriteError(err)
+			return
+		}
+	}
+
+	for {
+		select {
+		case body, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			select {
+			case <-c.evicted:
+				c.conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "send buffer overflow"))
+				return
+			default:
+			}
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, body); err != nil {
+				logWriteError(err)
+				return
+			}
+
+		case body := <-c.control:
+			if err := c.write(body); err != nil {
+				logWriteError(err)
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// write sends one message over the client's transport.
+func (c *Client) write(body []byte) error {
+	if c.sse != nil {
+		return c.sse.event(body)
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(websocket.TextMessage, body)
+}
+
+func (c *Client) transport() string {
+	if c.sse != nil {
+		return "SSE"
+	}
+	return "WebSocket"
+}
+
+// readLoop handles subscribe/unsubscribe requests until the connection closes or stops answering pings.
+func (c *Client) readLoop() {
+	defer func() {
+		c.hub.unregister <- c
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(maxRequestSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		var req clientRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.reply("error", gin.H{"message": "invalid JSON"})
+			continue
+		}
+
+		c.mu.Lock()
+		switch req.Action {
+		case "subscribe":
+			err = c.subscription.Add(req.Topics)
+		case "unsubscribe":
+			err = c.subscription.Remove(req.Topics)
+		case "auth":
+			err = fmt.Errorf("connection is already authenticated")
+		default:
+			err = fmt.Errorf("unknown action %q: expected subscribe or unsubscribe", req.Action)
+		}
+		topics := c.subscription.Topics()
+		c.mu.Unlock()
+
+		if err != nil {
+			c.reply("error", gin.H{"message": err.Error()})
+			continue
+		}
+		c.reply("subscribed", gin.H{"topics": topics})
+	}
+}
+
+// logWriteError only logs actual errors, not expected disconnections.
+func logWriteError(err error) {
+	if !strings.Contains(err.Error(), "broken pipe") && !strings.Contains(err.Error(), "connection reset") {
+		log.Printf("WebSocket error: %v", err)
+	}
+}
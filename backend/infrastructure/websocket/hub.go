@@ -1,86 +1,341 @@
 package websocket
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
-	"strings"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"minesense-backend/domain/entities"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	// Allow all origins for now (CORS)
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+// deviceCacheTTL bounds how stale a device's zone or supervisor can be when routing messages.
+const deviceCacheTTL = time.Minute
+
+// deviceLookupTimeout bounds one device lookup; deviceRetryInterval is how soon a failed lookup
+// is retried, the last known routing info being used meanwhile.
+const (
+	deviceLookupTimeout = 2 * time.Second
+	deviceRetryInterval = 5 * time.Second
+)
+
+type cachedDevice struct {
+	info    DeviceInfo
+	expires time.Time
 }
 
+// Hub fans broadcast messages out to connected clients. Publishers never block: messages are
+// queued for the hub goroutine, which hands them to each client's bounded send buffer.
+// A client whose buffer is full is evicted rather than slowing everyone else down.
+// Every message is stamped with a sequence ID and the latest ones are kept so reconnecting
+// clients can resume where they left off.
 type Hub struct {
-	clients    map[*websocket.Conn]bool
-	broadcast  chan interface{}
-	register   chan *websocket.Conn
-	unregister chan *websocket.Conn
+	clients    map[*Client]bool
+	broadcast  chan entities.Event
+	inbound    chan []byte // messages relayed from other instances
+	outbound   chan []byte // messages waiting to be relayed to other instances
+	register   chan *Client
+	unregister chan *Client
 	mu         sync.Mutex
+	upgrader   websocket.Upgrader
+	sendBuffer int
+
+	// Replay state, guarded by mu. Messages with seq <= floor are no longer buffered.
+	seq        int64
+	floor      int64
+	history    []replayEntry
+	replaySize int
+
+	// Authenticate validates a JWT and returns who it belongs to, with the account's current role.
+	// It fails for disabled or deleted accounts. Connections are refused without it.
+	Authenticate func(ctx context.Context, token string) (Identity, error)
+	// Account returns a connected user's current role and whether the account is still active;
+	// when set, connections are re-checked every accountCheckInterval.
+	Account func(ctx context.Context, userID string) (role string, active bool, err error)
+	// DeviceInfo resolves a device ID for zone subscriptions and Supervisor and Worker scoping. Unknown
+	// devices resolve to an empty DeviceInfo; errors are lookup failures.
+	DeviceInfo func(ctx context.Context, deviceID string) (DeviceInfo, error)
+	devices    map[string]cachedDevice
+	devicesMu  sync.Mutex
+	// ReplayAlerts loads alerts created in (since, until] for clients resuming past the replay buffer.
+	ReplayAlerts func(ctx context.Context, since, until time.Time) ([]StoredAlert, error)
+	// Backend relays messages to and from other instances; set before Run.
+	Backend Backend
+
+	broadcastDropped atomic.Int64
+	messagesDropped  atomic.Int64
+	evictions        atomic.Int64
+	relayFailed      atomic.Int64
+	relayReceived    atomic.Int64
 }
 
-func NewHub() *Hub {
+// NewHub creates a hub whose broadcast queue holds queueSize messages, whose clients
+// each buffer up to sendBuffer outgoing messages, and which keeps the last replaySize
+// messages for clients that reconnect.
+func NewHub(queueSize, sendBuffer, replaySize int) *Hub {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	if sendBuffer < 1 {
+		sendBuffer = 1
+	}
+	if replaySize < 0 {
+		replaySize = 0
+	}
+	// Nothing sent before startup is buffered
+	start := time.Now().UnixMicro()
 	return &Hub{
-		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan interface{}),
-		register:   make(chan *websocket.Conn),
-		unregister: make(chan *websocket.Conn),
+		clients:    make(map[*Client]bool),
+		broadcast:  make(chan entities.Event, queueSize),
+		inbound:    make(chan []byte, queueSize),
+		outbound:   make(chan []byte, queueSize),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Subprotocols:    subprotocols,
+		},
+		sendBuffer: sendBuffer,
+		seq:        start,
+		floor:      start,
+		replaySize: replaySize,
+		devices:    make(map[string]cachedDevice),
 	}
 }
 
 func (h *Hub) Run() {
+	if h.Backend != nil {
+		go h.publishLoop()
+		go h.listenLoop()
+	}
+	if h.Account != nil {
+		go h.accountLoop()
+	}
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
+			if client.resume {
+				// Snapshot under the same lock as registration so nothing is missed or sent twice
+				client.backlog, client.gap = h.since(client.lastSeq)
+				client.floor = h.floor
+				client.resumeSeq = h.seq
+			}
 			h.mu.Unlock()
-			log.Println("New WebSocket client connected")
+			close(client.registered)
+			log.Printf("New %s client connected", client.transport())
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				client.Close()
-				log.Println("WebSocket client disconnected")
+				h.remove(client)
+				log.Printf("%s client disconnected", client.transport())
 			}
 			h.mu.Unlock()
 
-		case message := <-h.broadcast:
-			h.mu.Lock()
-			for client := range h.clients {
-				err := client.WriteJSON(message)
-				if err != nil {
-					// Only log actual errors, not expected disconnections
-					if !strings.Contains(err.Error(), "broken pipe") && !strings.Contains(err.Error(), "connection reset") {
-						log.Printf("WebSocket error: %v", err)
-					}
-					client.Close()
-					delete(h.clients, client)
-				}
+		case event := <-h.broadcast:
+			envelope, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("WebSocket: failed to encode %s event: %v", event.Type, err)
+				continue
 			}
-			h.mu.Unlock()
+			h.fanOut(envelope)
+			h.relay(envelope)
+
+		case envelope := <-h.inbound:
+			h.fanOut(envelope)
+		}
+	}
+}
+
+// fanOut stamps an encoded event with the next sequence ID, keeps it for replay and hands it
+// to every local client that wants it, in the protocol version the client negotiated.
+// Sequence IDs are assigned per instance.
+func (h *Hub) fanOut(envelope []byte) {
+	msg, err := render(envelope)
+	if err != nil {
+		log.Printf("WebSocket: dropping malformed event: %v", err)
+		return
+	}
+	// Look the device up before taking the lock, so a slow lookup does not block clients
+	h.resolve(&msg.meta)
+	meta := msg.meta
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	seq := h.nextSeq()
+	msg.v1, msg.v2 = withSeq(msg.v1, seq), withSeq(msg.v2, seq)
+	h.remember(replayEntry{seq: seq, msg: msg})
+	for client := range h.clients {
+		if !client.wants(h, &meta) {
+			continue
+		}
+		select {
+		case client.send <- msg.body(client.protocol):
+		default:
+			// The client is not keeping up; drop it instead of blocking the hub
+			h.evict(client)
+			log.Printf("%s: evicted slow client (user %s)", client.transport(), client.identity.UserID)
 		}
 	}
 }
 
-func (h *Hub) BroadcastData(data interface{}) {
-	h.broadcast <- data
+// remove unregisters a client and stops its writer once its backlog is sent. Callers hold h.mu.
+func (h *Hub) remove(client *Client) {
+	delete(h.clients, client)
+	close(client.send)
 }
 
+// evict disconnects a slow client immediately, counting its backlog and the current message as dropped.
+// Callers hold h.mu.
+func (h *Hub) evict(client *Client) {
+	h.messagesDropped.Add(int64(len(client.send)) + 1)
+	h.evictions.Add(1)
+	close(client.evicted)
+	h.remove(client)
+}
+
+// BroadcastData queues an event for delivery without blocking the caller.
+// If the hub is too far behind the event is dropped and counted.
+func (h *Hub) BroadcastData(event entities.Event) {
+	select {
+	case h.broadcast <- event:
+	default:
+		h.broadcastDropped.Add(1)
+	}
+}
+
+// HandleWebSocket upgrades an authenticated request. The JWT comes from the Authorization header,
+// the token query parameter, or (for browsers that cannot set headers) the first message.
 func (h *Hub) HandleWebSocket(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if h.Authenticate == nil {
+		log.Println("WebSocket: no authenticator configured, refusing connection")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "WebSocket authentication is not configured"})
+		return
+	}
+
+	protocol, err := queryProtocol(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var identity Identity
+	lastSeq, resume := queryLastSeq(c)
+	token := requestToken(c)
+	if token != "" {
+		if identity, err = h.Authenticate(c.Request.Context(), token); err != nil {
+			respondAuthError(c, err)
+			return
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade to WebSocket: %v", err)
 		return
 	}
-	h.register <- conn
+
+	// A subprotocol (Sec-WebSocket-Protocol: minesense.v2) takes precedence over ?protocol=
+	if version, ok := subprotocolVersion(conn.Subprotocol()); ok {
+		protocol = version
+	}
+
+	if token == "" {
+		var req clientRequest
+		if identity, req, err = h.awaitAuth(conn); err != nil {
+			rejectConn(conn, protocol, err.Error())
+			return
+		}
+		if req.Protocol != 0 {
+			if !supportedProtocol(req.Protocol) {
+				rejectConn(conn, protocol, fmt.Sprintf("unsupported protocol version %d (supported: %d, %d)", req.Protocol, ProtocolV1, ProtocolV2))
+				return
+			}
+			protocol = req.Protocol
+		}
+		if req.LastSeq != nil {
+			lastSeq, resume = *req.LastSeq, true
+		}
+		conn.WriteMessage(websocket.TextMessage, controlMessage(protocol, "authenticated", gin.H{
+			"user_id": identity.UserID, "role": identity.Role, "protocol": protocol,
+		}))
+	}
+
+	client := newClient(h, conn, identity)
+	client.protocol = protocol
+	client.resume, client.lastSeq = resume, lastSeq
+	h.register <- client
+	// Start writing once any replay backlog has been captured
+	<-client.registered
+	go client.writePump()
+	go client.readLoop()
+}
+
+// Clients returns the number of connected clients.
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// QueueDepth returns the number of messages waiting for the hub goroutine.
+func (h *Hub) QueueDepth() int { return len(h.broadcast) + len(h.inbound) }
+
+func (h *Hub) BroadcastDropped() int64 { return h.broadcastDropped.Load() }
+func (h *Hub) MessagesDropped() int64  { return h.messagesDropped.Load() }
+func (h *Hub) Evictions() int64        { return h.evictions.Load() }
+func (h *Hub) RelayFailed() int64      { return h.relayFailed.Load() }
+func (h *Hub) RelayReceived() int64    { return h.relayReceived.Load() }
+
+// queryLastSeq reads the last_seq query parameter of a resuming client.
+func queryLastSeq(c *gin.Context) (int64, bool) {
+	seq, err := strconv.ParseInt(c.Query("last_seq"), 10, 64)
+	return seq, err == nil
+}
+
+// resolve fills in the zone, Supervisor and Worker of the message's device, once.
+func (h *Hub) resolve(meta *messageMeta) {
+	if meta.resolved {
+		return
+	}
+	info := h.deviceInfo(meta.DeviceID)
+	meta.Zone, meta.Supervisor, meta.Worker, meta.resolved = info.Zone, info.SupervisorID, info.WorkerID, true
+}
+
+// deviceInfo returns the cached routing info of a device, resolving it on a miss. Lookups run
+// without holding any lock. If one fails, the last known info is used until a retry shortly after.
+func (h *Hub) deviceInfo(deviceID string) DeviceInfo {
+	if h.DeviceInfo == nil || deviceID == "" {
+		return DeviceInfo{}
+	}
+	h.devicesMu.Lock()
+	cached, ok := h.devices[deviceID]
+	h.devicesMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.info
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deviceLookupTimeout)
+	info, err := h.DeviceInfo(ctx, deviceID)
+	cancel()
+	expires := time.Now().Add(deviceCacheTTL)
+	if err != nil {
+		log.Printf("WebSocket: failed to look up device %s: %v", deviceID, err)
+		info, expires = cached.info, time.Now().Add(deviceRetryInterval)
+	}
+	h.devicesMu.Lock()
+	h.devices[deviceID] = cachedDevice{info: info, expires: expires}
+	h.devicesMu.Unlock()
+	return info
 }
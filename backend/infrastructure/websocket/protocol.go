@@ -0,0 +1,173 @@
+package websocket
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"minesense-backend/domain/entities"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Protocol versions a client can negotiate. Version 1, the default, is the original ad-hoc
+// format kept for existing clients; version 2 is the entities.Event envelope.
+const (
+	ProtocolV1 = 1
+	ProtocolV2 = entities.EventProtocolVersion
+)
+
+// subprotocolPrefix names the WebSocket subprotocols, e.g. "minesense.v2".
+const subprotocolPrefix = "minesense.v"
+
+// subprotocols are offered in order of preference.
+var subprotocols = []string{subprotocolPrefix + "2", subprotocolPrefix + "1"}
+
+//go:embed schema/events.v2.schema.json
+var eventSchema []byte
+
+// ServeSchema publishes the JSON schema of the version 2 envelope and every event type.
+func ServeSchema(c *gin.Context) {
+	c.Data(http.StatusOK, "application/schema+json", eventSchema)
+}
+
+func supportedProtocol(version int) bool {
+	return version == ProtocolV1 || version == ProtocolV2
+}
+
+// queryProtocol reads the ?protocol= parameter; clients that do not ask get version 1.
+func queryProtocol(c *gin.Context) (int, error) {
+	param := c.Query("protocol")
+	if param == "" {
+		return ProtocolV1, nil
+	}
+	version, err := strconv.Atoi(param)
+	if err != nil || !supportedProtocol(version) {
+		return 0, fmt.Errorf("unsupported protocol version %q (supported: %d, %d)", param, ProtocolV1, ProtocolV2)
+	}
+	return version, nil
+}
+
+// subprotocolVersion maps a negotiated WebSocket subprotocol to its protocol version.
+func subprotocolVersion(subprotocol string) (int, bool) {
+	version, err := strconv.Atoi(strings.TrimPrefix(subprotocol, subprotocolPrefix))
+	return version, err == nil && strings.HasPrefix(subprotocol, subprotocolPrefix) && supportedProtocol(version)
+}
+
+// wireEvent is an entities.Event as received from a publisher or another instance.
+type wireEvent struct {
+	Type      string          `json:"type"`
+	Version   int             `json:"version"`
+	ID        string          `json:"id"`
+	Timestamp time.Time       `json:"timestamp"`
+	DeviceID  string          `json:"device_id"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// rendered is an event encoded once per protocol version, with its routing attributes.
+type rendered struct {
+	v1, v2 []byte
+	meta   messageMeta
+}
+
+func (r rendered) body(version int) []byte {
+	if version == ProtocolV2 {
+		return r.v2
+	}
+	return r.v1
+}
+
+// render decodes an encoded envelope and prepares both wire formats.
+func render(envelope []byte) (rendered, error) {
+	var event wireEvent
+	if err := json.Unmarshal(envelope, &event); err != nil {
+		return rendered{}, err
+	}
+	v1, err := json.Marshal(legacyMessage(event))
+	if err != nil {
+		return rendered{}, err
+	}
+	return rendered{v1: v1, v2: envelope, meta: eventMeta(event)}, nil
+}
+
+// eventMeta extracts the attributes used for topic matching and Supervisor scoping.
+func eventMeta(event wireEvent) messageMeta {
+	meta := messageMeta{Type: event.Type, DeviceID: event.DeviceID}
+	if event.Type == entities.EventAlert {
+		var alert struct {
+			ID       string `json:"id"`
+			Severity string `json:"severity"`
+		}
+		_ = json.Unmarshal(event.Payload, &alert)
+		meta.AlertID, meta.Severity = alert.ID, alert.Severity
+	}
+	return meta
+}
+
+// legacyMessage reshapes an envelope into the version 1 message of the same type.
+func legacyMessage(event wireEvent) map[string]interface{} {
+	switch event.Type {
+	case entities.EventSensorUpdate:
+		var p entities.SensorUpdatePayload
+		_ = json.Unmarshal(event.Payload, &p)
+		return map[string]interface{}{
+			"type":        event.Type,
+			"device_id":   event.DeviceID,
+			"sensor_type": p.SensorType,
+			"payload":     p.Readings,
+			"timestamp":   event.Timestamp,
+		}
+	case entities.EventAlert:
+		return map[string]interface{}{"type": event.Type, "alert": event.Payload}
+	case entities.EventImageUpdate:
+		var p entities.ImageUpdatePayload
+		_ = json.Unmarshal(event.Payload, &p)
+		return map[string]interface{}{
+			"type": event.Type,
+			"payload": map[string]interface{}{
+				"device_id":  event.DeviceID,
+				"image_url":  p.ImageURL,
+				"created_at": event.Timestamp,
+			},
+		}
+	case entities.EventDeviceCommand:
+		var p entities.DeviceCommandPayload
+		_ = json.Unmarshal(event.Payload, &p)
+		return map[string]interface{}{
+			"type":      event.Type,
+			"device_id": event.DeviceID,
+			"command":   p.Command,
+			"is_active": p.IsActive,
+			"timestamp": event.Timestamp,
+		}
+	default:
+		return map[string]interface{}{
+			"type":      event.Type,
+			"device_id": event.DeviceID,
+			"payload":   event.Payload,
+			"timestamp": event.Timestamp,
+		}
+	}
+}
+
+// controlMessage encodes a message about the connection itself (authenticated, subscribed, error,
+// replay_complete): flat in version 1, an envelope with the fields as payload in version 2.
+func controlMessage(version int, messageType string, fields map[string]interface{}) []byte {
+	var v interface{}
+	if version == ProtocolV2 {
+		v = entities.Event{Type: messageType, Version: ProtocolV2, ID: uuid.New(), Timestamp: time.Now(), Payload: fields}
+	} else {
+		flat := map[string]interface{}{"type": messageType}
+		for k, field := range fields {
+			flat[k] = field
+		}
+		v = flat
+	}
+	body, _ := json.Marshal(v)
+	return body
+}
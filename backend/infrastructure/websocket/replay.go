@@ -0,0 +1,166 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"minesense-backend/domain/entities"
+)
+
+// replayTimeout bounds the database lookup of alerts missed while a client was offline.
+const replayTimeout = 10 * time.Second
+
+// Sequence IDs are microseconds since the Unix epoch, bumped to stay strictly increasing.
+// They therefore keep growing across restarts and map back to a time for database replay.
+func seqTime(seq int64) time.Time { return time.UnixMicro(seq) }
+
+// nextSeq assigns the sequence ID of the next broadcast. Called from Run only.
+func (h *Hub) nextSeq() int64 {
+	seq := time.Now().UnixMicro()
+	if seq <= h.seq {
+		seq = h.seq + 1
+	}
+	h.seq = seq
+	return seq
+}
+
+// withSeq adds "seq" to a JSON object.
+func withSeq(body []byte, seq int64) []byte {
+	if len(body) < 2 || body[0] != '{' {
+		return body
+	}
+	prefix := fmt.Sprintf(`{"seq":%d`, seq)
+	if body[1] != '}' {
+		prefix += ","
+	}
+	return append([]byte(prefix), body[1:]...)
+}
+
+// bodySeq reads back the sequence ID added by withSeq.
+func bodySeq(body []byte) (int64, bool) {
+	rest, ok := bytes.CutPrefix(body, []byte(`{"seq":`))
+	if !ok {
+		return 0, false
+	}
+	end := bytes.IndexAny(rest, ",}")
+	if end < 0 {
+		return 0, false
+	}
+	seq, err := strconv.ParseInt(string(rest[:end]), 10, 64)
+	return seq, err == nil
+}
+
+// replayEntry is a broadcast message kept for clients that reconnect with last_seq.
+type replayEntry struct {
+	seq int64
+	msg rendered
+}
+
+// StoredAlert is an alert loaded from the database for a client that was offline longer
+// than the replay buffer covers.
+type StoredAlert struct {
+	ID        string
+	CreatedAt time.Time
+	Event     entities.Event
+}
+
+// remember appends a message to the replay buffer, forgetting the oldest once it is full. Callers hold h.mu.
+func (h *Hub) remember(entry replayEntry) {
+	if h.replaySize == 0 {
+		h.floor = entry.seq
+		return
+	}
+	if len(h.history) == h.replaySize {
+		h.floor = h.history[0].seq
+		h.history = h.history[1:]
+	}
+	h.history = append(h.history, entry)
+}
+
+// since returns the buffered messages after lastSeq, and whether older messages the client
+// missed have already left the buffer. Callers hold h.mu.
+func (h *Hub) since(lastSeq int64) (backlog []replayEntry, gap bool) {
+	for i, entry := range h.history {
+		if entry.seq > lastSeq {
+			backlog = append(backlog, h.history[i:]...)
+			break
+		}
+	}
+	return backlog, lastSeq < h.floor
+}
+
+// replay sends a resuming client what it missed, oldest first, before live streaming starts:
+// alerts from the database if it was gone longer than the buffer covers, then the buffered messages.
+// It ends with {"type": "replay_complete"}, where complete is false if non-alert messages were lost.
+func (c *Client) replay() error {
+	h := c.hub
+	replayed := 0
+	if c.gap {
+		buffered := map[string]bool{}
+		for _, entry := range c.backlog {
+			if entry.msg.meta.AlertID != "" {
+				buffered[entry.msg.meta.AlertID] = true
+			}
+		}
+		for _, body := range c.missedAlerts(buffered) {
+			if err := c.write(body); err != nil {
+				return err
+			}
+			replayed++
+		}
+	}
+	for _, entry := range c.backlog {
+		meta := entry.msg.meta
+		if !c.wants(h, &meta) {
+			continue
+		}
+		if err := c.write(entry.msg.body(c.protocol)); err != nil {
+			return err
+		}
+		replayed++
+	}
+	c.backlog = nil
+
+	return c.write(controlMessage(c.protocol, "replay_complete", map[string]interface{}{
+		"last_seq": c.resumeSeq,
+		"replayed": replayed,
+		"complete": !c.gap,
+	}))
+}
+
+// missedAlerts loads alerts raised between the client's last_seq and the start of the replay buffer.
+func (c *Client) missedAlerts(buffered map[string]bool) [][]byte {
+	h := c.hub
+	if h.ReplayAlerts == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+	defer cancel()
+	alerts, err := h.ReplayAlerts(ctx, seqTime(c.lastSeq), seqTime(c.floor))
+	if err != nil {
+		log.Printf("WebSocket: failed to load missed alerts: %v", err)
+		return nil
+	}
+
+	var bodies [][]byte
+	for _, alert := range alerts {
+		if buffered[alert.ID] {
+			continue
+		}
+		envelope, err := json.Marshal(alert.Event)
+		if err != nil {
+			continue
+		}
+		msg, err := render(envelope)
+		if err != nil || !c.wants(h, &msg.meta) {
+			continue
+		}
+		bodies = append(bodies, withSeq(msg.body(c.protocol), alert.CreatedAt.UnixMicro()))
+	}
+	return bodies
+}
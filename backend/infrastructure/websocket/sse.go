@@ -0,0 +1,155 @@
+package websocket
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sseHeartbeat keeps proxies from closing an idle event stream.
+	sseHeartbeat = 15 * time.Second
+	// sseRetry is the reconnect delay suggested to EventSource clients, in milliseconds.
+	sseRetry = 3000
+)
+
+// sseStream writes hub messages as Server-Sent Events. The sequence ID becomes the event ID,
+// so browsers resume with Last-Event-ID after reconnecting.
+type sseStream struct {
+	w  gin.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (s *sseStream) event(body []byte) error {
+	var buf bytes.Buffer
+	if seq, ok := bodySeq(body); ok {
+		fmt.Fprintf(&buf, "id: %d\n", seq)
+	}
+	buf.WriteString("data: ")
+	buf.Write(body)
+	buf.WriteString("\n\n")
+	return s.send(buf.Bytes())
+}
+
+func (s *sseStream) comment(text string) error {
+	return s.send([]byte(": " + text + "\n\n"))
+}
+
+func (s *sseStream) send(b []byte) error {
+	s.rc.SetWriteDeadline(time.Now().Add(writeWait))
+	if _, err := s.w.Write(b); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// HandleSSE streams the same messages as the WebSocket as Server-Sent Events, for clients behind
+// proxies that break WebSocket upgrades. The JWT comes from the Authorization header or the token
+// query parameter, topics from the comma-separated topics parameter, the protocol version from
+// the protocol parameter, and the resume point from Last-Event-ID (or last_seq).
+func (h *Hub) HandleSSE(c *gin.Context) {
+	if h.Authenticate == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Event stream authentication is not configured"})
+		return
+	}
+	token := requestToken(c)
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
+		return
+	}
+	identity, err := h.Authenticate(c.Request.Context(), token)
+	if err != nil {
+		respondAuthError(c, err)
+		return
+	}
+
+	protocol, err := queryProtocol(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	subscription := Subscription{}
+	if err := subscription.Add(queryTopics(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	lastSeq, resume := queryLastSeq(c)
+	if id := c.GetHeader("Last-Event-ID"); id != "" {
+		lastSeq, err = strconv.ParseInt(id, 10, 64)
+		resume = err == nil
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable nginx response buffering
+	c.Header("X-Protocol-Version", strconv.Itoa(protocol))
+	c.Status(http.StatusOK)
+
+	stream := &sseStream{w: c.Writer, rc: http.NewResponseController(c.Writer)}
+	if err := stream.send([]byte(fmt.Sprintf("retry: %d\n\n", sseRetry))); err != nil {
+		return
+	}
+
+	client := newClient(h, nil, identity)
+	client.sse = stream
+	client.protocol = protocol
+	client.subscription = subscription
+	client.resume, client.lastSeq = resume, lastSeq
+	h.register <- client
+	defer func() { h.unregister <- client }()
+	<-client.registered
+
+	if client.resume {
+		if err := client.replay(); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case body, ok := <-client.send:
+			select {
+			case <-client.evicted:
+				// EventSource reconnects on its own and resumes from the last event ID
+				client.write(controlMessage(protocol, "error", gin.H{"message": "send buffer overflow"}))
+				return
+			default:
+			}
+			if !ok {
+				return
+			}
+			if err := client.write(body); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if err := stream.comment("ping"); err != nil {
+				return
+			}
+
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// queryTopics collects topics given as ?topics=a,b or repeated topics parameters.
+func queryTopics(c *gin.Context) []string {
+	var topics []string
+	for _, param := range c.QueryArray("topics") {
+		for _, topic := range strings.Split(param, ",") {
+			if topic = strings.TrimSpace(topic); topic != "" {
+				topics = append(topics, topic)
+			}
+		}
+	}
+	return topics
+}
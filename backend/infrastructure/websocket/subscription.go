@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Topic kinds a client can subscribe to, written as "<kind>:<value>", e.g. "device:<uuid>",
+// "zone:Shaft A", "type:alert" or "severity:Critical".
+const (
+	TopicDevice   = "device"
+	TopicZone     = "zone"
+	TopicType     = "type"
+	TopicSeverity = "severity"
+)
+
+var topicKinds = map[string]bool{
+	TopicDevice:   true,
+	TopicZone:     true,
+	TopicType:     true,
+	TopicSeverity: true,
+}
+
+// clientRequest is a control message sent by a client over the socket.
+type clientRequest struct {
+	Action   string   `json:"action"` // auth, subscribe, unsubscribe
+	Topics   []string `json:"topics"`
+	Token    string   `json:"token"`
+	LastSeq  *int64   `json:"last_seq"` // auth only: resume after this sequence ID
+	Protocol int      `json:"protocol"` // auth only: protocol version to use
+}
+
+// messageMeta holds the routing attributes of a broadcast message.
+type messageMeta struct {
+	Type     string
+	DeviceID string
+	Zone     string
+	Severity string
+	AlertID  string
+	// Supervisor and Worker are the user IDs of the device's Supervisor and Worker; they and
+	// Zone are set once resolved.
+	Supervisor string
+	Worker     string
+	resolved   bool
+}
+
+// Subscription is the set of topics a client asked for, grouped by kind.
+// Within a kind any value matches; across kinds all must match. A kind only constrains
+// messages that carry that attribute, so "severity:Critical" filters alerts but not sensor updates.
+// An empty subscription receives everything, which keeps existing clients working.
+type Subscription map[string]map[string]bool
+
+func parseTopic(topic string) (kind, value string, err error) {
+	kind, value, ok := strings.Cut(topic, ":")
+	if !ok || value == "" || !topicKinds[kind] {
+		return "", "", fmt.Errorf("invalid topic %q: expected device:<id>, zone:<name>, type:<message type> or severity:<level>", topic)
+	}
+	return kind, value, nil
+}
+
+func (s Subscription) Add(topics []string) error {
+	for _, topic := range topics {
+		kind, value, err := parseTopic(topic)
+		if err != nil {
+			return err
+		}
+		if s[kind] == nil {
+			s[kind] = map[string]bool{}
+		}
+		s[kind][value] = true
+	}
+	return nil
+}
+
+func (s Subscription) Remove(topics []string) error {
+	for _, topic := range topics {
+		kind, value, err := parseTopic(topic)
+		if err != nil {
+			return err
+		}
+		delete(s[kind], value)
+		if len(s[kind]) == 0 {
+			delete(s, kind)
+		}
+	}
+	return nil
+}
+
+// Topics lists the current subscription as topic strings.
+func (s Subscription) Topics() []string {
+	topics := []string{}
+	for kind, values := range s {
+		for value := range values {
+			topics = append(topics, kind+":"+value)
+		}
+	}
+	return topics
+}
+
+func (s Subscription) Matches(meta messageMeta) bool {
+	// A device message whose zone is unknown does not match a zone filter
+	zoneOK := len(s[TopicZone]) == 0 || meta.DeviceID == "" || s[TopicZone][meta.Zone]
+	return zoneOK &&
+		s.matchKind(TopicType, meta.Type) &&
+		s.matchKind(TopicDevice, meta.DeviceID) &&
+		s.matchKind(TopicSeverity, meta.Severity)
+}
+
+func (s Subscription) matchKind(kind, value string) bool {
+	values := s[kind]
+	if len(values) == 0 || value == "" {
+		return true
+	}
+	return values[value]
+}
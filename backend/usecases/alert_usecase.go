@@ -1,6 +1,9 @@
 package usecases
 
 import (
+	"context"
+	"time"
+
 	"minesense-backend/domain/entities"
 	"minesense-backend/domain/interfaces"
 
@@ -15,10 +18,15 @@ func NewAlertUseCase(alertRepo interfaces.AlertRepository) *AlertUseCase {
 	return &AlertUseCase{AlertRepo: alertRepo}
 }
 
-func (uc *AlertUseCase) GetAllAlerts() ([]entities.Alert, error) {
-	return uc.AlertRepo.FindAll()
+func (uc *AlertUseCase) GetAllAlerts(ctx context.Context) ([]entities.Alert, error) {
+	return uc.AlertRepo.FindAll(ctx)
+}
+
+func (uc *AlertUseCase) GetAlertsByDevice(ctx context.Context, deviceID uuid.UUID) ([]entities.Alert, error) {
+	return uc.AlertRepo.FindByDeviceID(ctx, deviceID)
 }
 
-func (uc *AlertUseCase) GetAlertsByDevice(deviceID uuid.UUID) ([]entities.Alert, error) {
-	return uc.AlertRepo.FindByDeviceID(deviceID)
+// GetAlertsBetween returns alerts created in (since, until], oldest first.
+func (uc *AlertUseCase) GetAlertsBetween(ctx context.Context, since, until time.Time) ([]entities.Alert, error) {
+	return uc.AlertRepo.FindBetween(ctx, since, until)
 }
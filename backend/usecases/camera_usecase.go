@@ -0,0 +1,211 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRetention       = errors.New("retention_hours must not be negative")
+	ErrInvalidMotionThreshold = errors.New("motion_threshold must be greater than 0 and at most 1")
+	ErrInvalidTargetFPS       = errors.New("target_fps must be greater than 0 and at most 30")
+	ErrNotCamera              = errors.New("device is not registered as a camera")
+)
+
+// targetFPSCacheTTL bounds how long another instance's change to a camera's frame rate takes
+// to be enforced here; changes made through this instance apply at once.
+const targetFPSCacheTTL = 30 * time.Second
+
+// CameraDefaults apply to cameras without their own settings.
+type CameraDefaults struct {
+	// Retention is how long frames are kept (0 keeps them forever).
+	Retention time.Duration
+	// MotionThreshold is the motion score (0-1) that counts as motion.
+	MotionThreshold float64
+	// TargetFPS is the frame rate cameras are asked to send (0 for no limit).
+	TargetFPS float64
+}
+
+// CameraConfig is a camera's own settings together with the values in effect.
+type CameraConfig struct {
+	entities.CameraSettings
+	EffectiveRetentionHours  int     `json:"effective_retention_hours"`
+	EffectiveMotionThreshold float64 `json:"effective_motion_threshold"`
+	EffectiveTargetFPS       float64 `json:"effective_target_fps"`
+}
+
+type cachedFPS struct {
+	fps     float64
+	expires time.Time
+}
+
+// CameraUseCase manages per-camera settings on top of the server-wide defaults, and sends
+// cameras the frame rate they should capture at.
+type CameraUseCase struct {
+	CameraRepo interfaces.CameraSettingsRepository
+	DeviceRepo interfaces.DeviceRepository
+	Hub        interfaces.Broadcaster
+	Defaults   CameraDefaults
+
+	mu        sync.Mutex
+	targetFPS map[uuid.UUID]cachedFPS
+	cameras   map[uuid.UUID]bool
+}
+
+func NewCameraUseCase(cameraRepo interfaces.CameraSettingsRepository, deviceRepo interfaces.DeviceRepository, hub interfaces.Broadcaster, defaults CameraDefaults) *CameraUseCase {
+	return &CameraUseCase{
+		CameraRepo: cameraRepo,
+		DeviceRepo: deviceRepo,
+		Hub:        hub,
+		Defaults:   defaults,
+		targetFPS:  make(map[uuid.UUID]cachedFPS),
+		cameras:    make(map[uuid.UUID]bool),
+	}
+}
+
+// CheckCamera returns ErrNotFound for an unknown device and ErrNotCamera for a device not
+// registered as a camera. Frames arrive several times a second and a device's camera flag is
+// set at registration, so confirmed cameras are remembered.
+func (uc *CameraUseCase) CheckCamera(ctx context.Context, deviceID uuid.UUID) error {
+	uc.mu.Lock()
+	known := uc.cameras[deviceID]
+	uc.mu.Unlock()
+	if known {
+		return nil
+	}
+
+	device, err := uc.DeviceRepo.FindByID(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if !device.HasCamera {
+		return ErrNotCamera
+	}
+	uc.mu.Lock()
+	uc.cameras[deviceID] = true
+	uc.mu.Unlock()
+	return nil
+}
+
+// Retention is how long the camera's frames are kept; settings may be nil.
+func (uc *CameraUseCase) Retention(settings *entities.CameraSettings) time.Duration {
+	if settings != nil && settings.RetentionHours != nil {
+		return time.Duration(*settings.RetentionHours) * time.Hour
+	}
+	return uc.Defaults.Retention
+}
+
+// effectiveFPS is the camera's frame rate (0 for no limit); settings may be nil.
+func (uc *CameraUseCase) effectiveFPS(settings *entities.CameraSettings) float64 {
+	if settings != nil && settings.TargetFPS != nil {
+		return *settings.TargetFPS
+	}
+	return uc.Defaults.TargetFPS
+}
+
+// TargetFPS is the frame rate the camera should send at (0 for no limit). It is looked up on
+// every frame, so it is cached briefly.
+func (uc *CameraUseCase) TargetFPS(ctx context.Context, deviceID uuid.UUID) float64 {
+	uc.mu.Lock()
+	cached, ok := uc.targetFPS[deviceID]
+	uc.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.fps
+	}
+
+	settings, err := uc.CameraRepo.FindByDeviceID(ctx, deviceID)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		settings, err = nil, nil
+	}
+	if err != nil {
+		log.Printf("Failed to load camera settings of %s: %v", deviceID, err)
+		return uc.Defaults.TargetFPS
+	}
+	fps := uc.effectiveFPS(settings)
+	uc.cacheFPS(deviceID, fps)
+	return fps
+}
+
+func (uc *CameraUseCase) cacheFPS(deviceID uuid.UUID, fps float64) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.targetFPS[deviceID] = cachedFPS{fps: fps, expires: time.Now().Add(targetFPSCacheTTL)}
+}
+
+func (uc *CameraUseCase) config(settings *entities.CameraSettings) *CameraConfig {
+	config := &CameraConfig{
+		CameraSettings:           *settings,
+		EffectiveRetentionHours:  int(uc.Retention(settings) / time.Hour),
+		EffectiveMotionThreshold: uc.Defaults.MotionThreshold,
+		EffectiveTargetFPS:       uc.effectiveFPS(settings),
+	}
+	if settings.MotionThreshold != nil {
+		config.EffectiveMotionThreshold = *settings.MotionThreshold
+	}
+	return config
+}
+
+// findSettings returns the camera's settings, or empty ones for a camera never configured.
+func (uc *CameraUseCase) findSettings(ctx context.Context, deviceID uuid.UUID) (*entities.CameraSettings, error) {
+	settings, err := uc.CameraRepo.FindByDeviceID(ctx, deviceID)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		return &entities.CameraSettings{DeviceID: deviceID}, nil
+	}
+	return settings, err
+}
+
+// GetSettings returns the camera's settings; a camera never configured gets the defaults.
+func (uc *CameraUseCase) GetSettings(ctx context.Context, deviceID uuid.UUID) (*CameraConfig, error) {
+	if _, err := uc.DeviceRepo.FindByID(ctx, deviceID); err != nil {
+		return nil, err
+	}
+	settings, err := uc.findSettings(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.config(settings), nil
+}
+
+// UpdateSettings replaces the camera's settings; nil fields revert to the defaults. A change of
+// frame rate is sent to the camera as a "set_fps" command.
+func (uc *CameraUseCase) UpdateSettings(ctx context.Context, deviceID uuid.UUID, settings *entities.CameraSettings) (*CameraConfig, error) {
+	// 1. Validate
+	if settings.RetentionHours != nil && *settings.RetentionHours < 0 {
+		return nil, ErrInvalidRetention
+	}
+	if t := settings.MotionThreshold; t != nil && (*t <= 0 || *t > 1) {
+		return nil, ErrInvalidMotionThreshold
+	}
+	if fps := settings.TargetFPS; fps != nil && (*fps <= 0 || *fps > 30) {
+		return nil, ErrInvalidTargetFPS
+	}
+	if _, err := uc.DeviceRepo.FindByID(ctx, deviceID); err != nil {
+		return nil, err
+	}
+
+	// 2. Save, remembering the frame rate in effect before
+	previous, err := uc.findSettings(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	settings.DeviceID = deviceID
+	if err := uc.CameraRepo.Save(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	// 3. Enforce and announce a new frame rate
+	fps := uc.effectiveFPS(settings)
+	uc.cacheFPS(deviceID, fps)
+	if fps != uc.effectiveFPS(previous) {
+		uc.Hub.BroadcastData(entities.NewTargetFPSCommandEvent(deviceID, fps))
+	}
+	return uc.config(settings), nil
+}
@@ -1,42 +1,121 @@
 package usecases
 
 import (
+	"context"
+	"errors"
 	"minesense-backend/domain/entities"
 	"minesense-backend/domain/interfaces"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// buzzerCacheTTL bounds how stale a cached buzzer state may be when served to devices.
+const buzzerCacheTTL = 2 * time.Second
+
+type buzzerCacheEntry struct {
+	active    bool
+	expiresAt time.Time
+}
+
 type DeviceUseCase struct {
 	DeviceRepo interfaces.DeviceRepository
+
+	buzzerMu    sync.RWMutex
+	buzzerCache map[uuid.UUID]buzzerCacheEntry
+
+	knownMu sync.RWMutex
+	known   map[uuid.UUID]bool
 }
 
 func NewDeviceUseCase(deviceRepo interfaces.DeviceRepository) *DeviceUseCase {
-	return &DeviceUseCase{DeviceRepo: deviceRepo}
+	return &DeviceUseCase{
+		DeviceRepo:  deviceRepo,
+		buzzerCache: make(map[uuid.UUID]buzzerCacheEntry),
+		known:       make(map[uuid.UUID]bool),
+	}
 }
 
-func (uc *DeviceUseCase) RegisterDevice(name, location string, supervisorID *uuid.UUID) (*entities.Device, error) {
+func (uc *DeviceUseCase) RegisterDevice(ctx context.Context, name, location string, supervisorID, workerID *uuid.UUID, hasCamera bool) (*entities.Device, error) {
 	device := &entities.Device{
 		DeviceName:   name,
 		Location:     location,
 		SupervisorID: supervisorID,
+		WorkerID:     workerID,
+		HasCamera:    hasCamera,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
-	err := uc.DeviceRepo.Create(device)
+	err := uc.DeviceRepo.Create(ctx, device)
 	return device, err
 }
 
-func (uc *DeviceUseCase) GetAllDevices() ([]entities.Device, error) {
-	return uc.DeviceRepo.FindAll()
+// Exists reports whether the device is registered. Devices are never deleted, so devices found
+// once are remembered and only unknown IDs reach the database.
+func (uc *DeviceUseCase) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	uc.knownMu.RLock()
+	known := uc.known[id]
+	uc.knownMu.RUnlock()
+	if known {
+		return true, nil
+	}
+
+	_, err := uc.DeviceRepo.FindByID(ctx, id)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	uc.knownMu.Lock()
+	uc.known[id] = true
+	uc.knownMu.Unlock()
+	return true, nil
+}
+
+func (uc *DeviceUseCase) GetAllDevices(ctx context.Context) ([]entities.Device, error) {
+	return uc.DeviceRepo.FindAll(ctx)
+}
+
+func (uc *DeviceUseCase) GetDeviceByID(ctx context.Context, id uuid.UUID) (*entities.Device, error) {
+	return uc.DeviceRepo.FindByID(ctx, id)
 }
 
-func (uc *DeviceUseCase) GetDeviceByID(id uuid.UUID) (*entities.Device, error) {
-	return uc.DeviceRepo.FindByID(id)
+// GetDevicesByZone returns the devices whose location matches the zone name.
+func (uc *DeviceUseCase) GetDevicesByZone(ctx context.Context, zone string) ([]entities.Device, error) {
+	return uc.DeviceRepo.FindByLocation(ctx, zone)
 }
 
-func (uc *DeviceUseCase) UpdateDevice(device *entities.Device) error {
+func (uc *DeviceUseCase) UpdateDevice(ctx context.Context, device *entities.Device) error {
 	device.UpdatedAt = time.Now()
-	return uc.DeviceRepo.Update(device)
+	if err := uc.DeviceRepo.Update(ctx, device); err != nil {
+		return err
+	}
+	uc.cacheBuzzerState(device.ID, device.BuzzerActive)
+	return nil
+}
+
+// BuzzerState returns whether the device's buzzer is active.
+// Devices poll this on every upload, so the value is cached briefly to keep it off the database hot path.
+func (uc *DeviceUseCase) BuzzerState(ctx context.Context, id uuid.UUID) bool {
+	uc.buzzerMu.RLock()
+	entry, ok := uc.buzzerCache[id]
+	uc.buzzerMu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.active
+	}
+
+	device, err := uc.DeviceRepo.FindByID(ctx, id)
+	if err != nil {
+		return false
+	}
+	uc.cacheBuzzerState(id, device.BuzzerActive)
+	return device.BuzzerActive
+}
+
+func (uc *DeviceUseCase) cacheBuzzerState(id uuid.UUID, active bool) {
+	uc.buzzerMu.Lock()
+	uc.buzzerCache[id] = buzzerCacheEntry{active: active, expiresAt: time.Now().Add(buzzerCacheTTL)}
+	uc.buzzerMu.Unlock()
 }
@@ -0,0 +1,189 @@
+package usecases
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+// evidenceInterval is how often the evidence job links newly stored frames to recent alerts.
+const evidenceInterval = 10 * time.Second
+
+// EvidencePolicy selects the alerts that get camera evidence and the frames linked to them.
+type EvidencePolicy struct {
+	// Frames taken from Before the alert until After it are linked.
+	Before time.Duration
+	After  time.Duration
+	// AlertTypes lists the alert types that collect evidence; "*" matches any type.
+	AlertTypes []string
+}
+
+func (p EvidencePolicy) applies(alertType string) bool {
+	for _, t := range p.AlertTypes {
+		if t == "*" || t == alertType {
+			return true
+		}
+	}
+	return false
+}
+
+// AlertEvidence is an alert with the camera frames linked to it.
+type AlertEvidence struct {
+	Alert       entities.Alert `json:"alert"`
+	WindowStart time.Time      `json:"window_start"`
+	WindowEnd   time.Time      `json:"window_end"`
+	// Complete is false while frames may still be linked (the window has not closed yet).
+	Complete bool             `json:"complete"`
+	Images   []entities.Image `json:"images"`
+}
+
+// EvidenceUseCase links alerts to the frames cameras in the same zone captured around them.
+// A background job revisits each alert until its window has closed, so frames uploaded after
+// the alert are linked too. Linking is idempotent, and linked frames are exempt from image retention.
+type EvidenceUseCase struct {
+	AlertRepo  interfaces.AlertRepository
+	DeviceRepo interfaces.DeviceRepository
+	ImageRepo  interfaces.ImageRepository
+	Images     *ImageUseCase
+	Policy     EvidencePolicy
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewEvidenceUseCase(alertRepo interfaces.AlertRepository, deviceRepo interfaces.DeviceRepository, imageRepo interfaces.ImageRepository, images *ImageUseCase, policy EvidencePolicy) *EvidenceUseCase {
+	return &EvidenceUseCase{
+		AlertRepo:  alertRepo,
+		DeviceRepo: deviceRepo,
+		ImageRepo:  imageRepo,
+		Images:     images,
+		Policy:     policy,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start runs the evidence job in the background until Stop is called.
+func (uc *EvidenceUseCase) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	uc.cancel = cancel
+	go func() {
+		defer close(uc.done)
+		ticker := time.NewTicker(evidenceInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				uc.RunOnce(ctx, time.Now())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	log.Printf("Alert evidence job started (frames from %s before to %s after %v alerts)", uc.Policy.Before, uc.Policy.After, uc.Policy.AlertTypes)
+}
+
+func (uc *EvidenceUseCase) Stop() {
+	uc.once.Do(func() {
+		if uc.cancel == nil {
+			return
+		}
+		uc.cancel()
+		<-uc.done
+	})
+}
+
+// RunOnce links frames to every alert whose window was still open during the last interval.
+// Each alert is therefore visited at least once after its window closed.
+func (uc *EvidenceUseCase) RunOnce(ctx context.Context, now time.Time) {
+	alerts, err := uc.AlertRepo.FindBetween(ctx, now.Add(-uc.Policy.After-2*evidenceInterval), now)
+	if err != nil {
+		log.Printf("Evidence: failed to load recent alerts: %v", err)
+		return
+	}
+	for i := range alerts {
+		if !uc.Policy.applies(alerts[i].AlertType) {
+			continue
+		}
+		if err := uc.Link(ctx, &alerts[i]); err != nil {
+			log.Printf("Evidence: failed to link frames to alert %s: %v", alerts[i].ID, err)
+		}
+	}
+}
+
+// window is the span of frames linked to an alert.
+func (uc *EvidenceUseCase) window(alert *entities.Alert) (time.Time, time.Time) {
+	return alert.CreatedAt.Add(-uc.Policy.Before), alert.CreatedAt.Add(uc.Policy.After)
+}
+
+// cameras returns the alert's device and every other device in its zone.
+func (uc *EvidenceUseCase) cameras(ctx context.Context, alert *entities.Alert) ([]uuid.UUID, error) {
+	device, err := uc.DeviceRepo.FindByID(ctx, alert.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	ids := []uuid.UUID{device.ID}
+	if device.Location == "" {
+		return ids, nil
+	}
+	zone, err := uc.DeviceRepo.FindByLocation(ctx, device.Location)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range zone {
+		if d.ID != device.ID {
+			ids = append(ids, d.ID)
+		}
+	}
+	return ids, nil
+}
+
+// Link records the frames stored so far in the alert's window as its evidence.
+func (uc *EvidenceUseCase) Link(ctx context.Context, alert *entities.Alert) error {
+	cameras, err := uc.cameras(ctx, alert)
+	if err != nil {
+		return err
+	}
+	start, end := uc.window(alert)
+	var imageIDs []uuid.UUID
+	for _, deviceID := range cameras {
+		images, err := uc.ImageRepo.FindByDevice(ctx, deviceID, start, end, 0)
+		if err != nil {
+			return err
+		}
+		for _, image := range images {
+			imageIDs = append(imageIDs, image.ID)
+		}
+	}
+	return uc.ImageRepo.LinkToAlert(ctx, alert.ID, imageIDs)
+}
+
+// GetEvidence returns an alert and the frames linked to it so far.
+func (uc *EvidenceUseCase) GetEvidence(ctx context.Context, alertID uuid.UUID) (*AlertEvidence, error) {
+	alert, err := uc.AlertRepo.FindByID(ctx, alertID)
+	if err != nil {
+		return nil, err
+	}
+	images, err := uc.ImageRepo.FindByAlert(ctx, alertID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range images {
+		uc.Images.withURL(&images[i])
+	}
+	start, end := uc.window(alert)
+	return &AlertEvidence{
+		Alert:       *alert,
+		WindowStart: start,
+		WindowEnd:   end,
+		Complete:    time.Now().After(end.Add(evidenceInterval)) || !uc.Policy.applies(alert.AlertType),
+		Images:      images,
+	}, nil
+}
@@ -0,0 +1,198 @@
+package usecases
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+// trafficWindow is how many seconds recent frame and byte rates are averaged over.
+const trafficWindow = 60
+
+// frameJitter is the fraction of a frame interval a frame may arrive early and still be kept,
+// so network jitter does not halve a camera that sends at exactly its target rate.
+const frameJitter = 0.25
+
+type trafficBucket struct {
+	second int64
+	frames uint64 // Frames kept
+	bytes  uint64 // Bytes received, whether the frame was kept or not
+}
+
+// cameraTraffic is what is known about a camera's uploads since startup.
+type cameraTraffic struct {
+	next                         time.Time // When the next frame is due at the target rate
+	received, accepted, dropped  uint64
+	bytesReceived, bytesAccepted uint64
+	lastFrame                    time.Time
+	recent                       [trafficWindow]trafficBucket
+}
+
+func (t *cameraTraffic) bucket(now time.Time) *trafficBucket {
+	second := now.Unix()
+	bucket := &t.recent[second%trafficWindow]
+	if bucket.second != second {
+		*bucket = trafficBucket{second: second}
+	}
+	return bucket
+}
+
+// CameraStats describes a camera's uploads. Counters cover frames received since the server
+// started; stored figures cover the frames currently kept.
+type CameraStats struct {
+	DeviceID       uuid.UUID `json:"device_id"`
+	TargetFPS      float64   `json:"target_fps"`
+	FramesReceived uint64    `json:"frames_received"`
+	FramesAccepted uint64    `json:"frames_accepted"`
+	FramesDropped  uint64    `json:"frames_dropped"`
+	BytesReceived  uint64    `json:"bytes_received"`
+	BytesAccepted  uint64    `json:"bytes_accepted"`
+	// RecentFPS (frames kept) and RecentBytesPerSecond (bytes received) average the last minute.
+	RecentFPS            float64    `json:"recent_fps"`
+	RecentBytesPerSecond float64    `json:"recent_bytes_per_second"`
+	LastFrameAt          *time.Time `json:"last_frame_at"`
+	StoredFrames         int64      `json:"stored_frames"`
+	StoredBytes          int64      `json:"stored_bytes"`
+}
+
+// FrameRateUseCase holds each camera to its target frame rate by dropping frames that arrive
+// early, and accounts for every camera's frames and bandwidth.
+type FrameRateUseCase struct {
+	Cameras    *CameraUseCase
+	DeviceRepo interfaces.DeviceRepository
+	ImageRepo  interfaces.ImageRepository
+
+	mu      sync.Mutex
+	traffic map[uuid.UUID]*cameraTraffic
+
+	droppedTotal  atomic.Uint64
+	receivedBytes atomic.Uint64
+}
+
+func NewFrameRateUseCase(cameras *CameraUseCase, deviceRepo interfaces.DeviceRepository, imageRepo interfaces.ImageRepository) *FrameRateUseCase {
+	return &FrameRateUseCase{
+		Cameras:    cameras,
+		DeviceRepo: deviceRepo,
+		ImageRepo:  imageRepo,
+		traffic:    make(map[uuid.UUID]*cameraTraffic),
+	}
+}
+
+// Received counts the bytes of an upload as soon as its camera is known, before quota, rate or
+// validity checks, so the camera's bandwidth includes uploads that are then refused.
+func (uc *FrameRateUseCase) Received(deviceID uuid.UUID, size int64) {
+	if size <= 0 {
+		return
+	}
+	uc.receivedBytes.Add(uint64(size))
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	// Cameras are tracked from their first stored frame, so unknown IDs cannot grow the map
+	if t, ok := uc.traffic[deviceID]; ok {
+		t.bytesReceived += uint64(size)
+		t.bucket(time.Now()).bytes += uint64(size)
+	}
+}
+
+// Admit decides whether a frame arriving now is kept, and returns the camera's target frame
+// rate (0 for no limit) to send back to it and the arrival time. A kept frame must be reported
+// with Accepted once stored, which moves the camera's schedule on; a dropped one is already counted.
+func (uc *FrameRateUseCase) Admit(ctx context.Context, deviceID uuid.UUID) (bool, float64, time.Time) {
+	fps := uc.Cameras.TargetFPS(ctx, deviceID)
+	now := time.Now()
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	t, ok := uc.traffic[deviceID]
+	if !ok || fps <= 0 {
+		return true, fps, now
+	}
+	interval := time.Duration(float64(time.Second) / fps)
+	if now.Before(t.next.Add(-time.Duration(frameJitter * float64(interval)))) {
+		t.received++
+		t.dropped++
+		uc.droppedTotal.Add(1)
+		return false, fps, now
+	}
+	return true, fps, now
+}
+
+// Accepted counts a frame of size bytes that arrived at `at`, was admitted and stored, and
+// schedules the camera's next frame.
+func (uc *FrameRateUseCase) Accepted(deviceID uuid.UUID, size int, fps float64, at time.Time) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	t, ok := uc.traffic[deviceID]
+	if !ok {
+		t = &cameraTraffic{}
+		uc.traffic[deviceID] = t
+	}
+	if fps > 0 {
+		// Keep to the camera's schedule; after a pause, start a new one
+		t.next = later(t.next, at).Add(time.Duration(float64(time.Second) / fps))
+	}
+	now := time.Now()
+	t.received++
+	t.accepted++
+	t.bytesAccepted += uint64(size)
+	t.lastFrame = now
+	t.bucket(now).frames++
+}
+
+// Stats returns the camera's upload statistics.
+func (uc *FrameRateUseCase) Stats(ctx context.Context, deviceID uuid.UUID) (*CameraStats, error) {
+	if _, err := uc.DeviceRepo.FindByID(ctx, deviceID); err != nil {
+		return nil, err
+	}
+	stats := &CameraStats{DeviceID: deviceID, TargetFPS: uc.Cameras.TargetFPS(ctx, deviceID)}
+
+	uc.mu.Lock()
+	if t, ok := uc.traffic[deviceID]; ok {
+		stats.FramesReceived, stats.FramesAccepted, stats.FramesDropped = t.received, t.accepted, t.dropped
+		stats.BytesReceived, stats.BytesAccepted = t.bytesReceived, t.bytesAccepted
+		lastFrame := t.lastFrame
+		stats.LastFrameAt = &lastFrame
+
+		// Average the complete seconds of the window
+		now := time.Now().Unix()
+		var frames, bytes uint64
+		for _, bucket := range t.recent {
+			if bucket.second < now && bucket.second >= now-trafficWindow {
+				frames += bucket.frames
+				bytes += bucket.bytes
+			}
+		}
+		stats.RecentFPS = float64(frames) / trafficWindow
+		stats.RecentBytesPerSecond = float64(bytes) / trafficWindow
+	}
+	uc.mu.Unlock()
+
+	var err error
+	stats.StoredFrames, stats.StoredBytes, err = uc.ImageRepo.Usage(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Dropped is the number of frames dropped for arriving above their camera's target rate.
+func (uc *FrameRateUseCase) Dropped() uint64 {
+	return uc.droppedTotal.Load()
+}
+
+// BytesReceived is the number of upload bytes received from all cameras, kept or not.
+func (uc *FrameRateUseCase) BytesReceived() uint64 {
+	return uc.receivedBytes.Load()
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
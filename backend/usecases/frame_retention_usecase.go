@@ -0,0 +1,138 @@
+package usecases
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+// FrameRetentionUseCase periodically deletes camera frames older than their camera's retention,
+// except frames linked to an alert as evidence.
+type FrameRetentionUseCase struct {
+	ImageRepo  interfaces.ImageRepository
+	DeviceRepo interfaces.DeviceRepository
+	Blobs      interfaces.BlobStore
+	Cameras    *CameraUseCase
+	Interval   time.Duration
+	BatchSize  int
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewFrameRetentionUseCase(imageRepo interfaces.ImageRepository, deviceRepo interfaces.DeviceRepository, blobs interfaces.BlobStore, cameras *CameraUseCase, interval time.Duration, batchSize int) *FrameRetentionUseCase {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+	return &FrameRetentionUseCase{
+		ImageRepo:  imageRepo,
+		DeviceRepo: deviceRepo,
+		Blobs:      blobs,
+		Cameras:    cameras,
+		Interval:   interval,
+		BatchSize:  batchSize,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start runs the frame retention job in the background until Stop is called.
+func (uc *FrameRetentionUseCase) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	uc.cancel = cancel
+	go func() {
+		defer close(uc.done)
+		ticker := time.NewTicker(uc.Interval)
+		defer ticker.Stop()
+
+		uc.RunOnce(ctx)
+		for {
+			select {
+			case <-ticker.C:
+				uc.RunOnce(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	log.Printf("Frame retention job started (every %s)", uc.Interval)
+}
+
+func (uc *FrameRetentionUseCase) Stop() {
+	uc.once.Do(func() {
+		if uc.cancel == nil {
+			return
+		}
+		uc.cancel()
+		<-uc.done
+	})
+}
+
+// RunOnce purges expired frames of every camera. Errors are logged; the next pass retries.
+func (uc *FrameRetentionUseCase) RunOnce(ctx context.Context) {
+	devices, err := uc.DeviceRepo.FindAll(ctx)
+	if err != nil {
+		log.Printf("Frame retention: failed to list devices: %v", err)
+		return
+	}
+	settings, err := uc.Cameras.CameraRepo.FindAll(ctx)
+	if err != nil {
+		log.Printf("Frame retention: failed to load camera settings: %v", err)
+		return
+	}
+	byDevice := make(map[uuid.UUID]*entities.CameraSettings, len(settings))
+	for i := range settings {
+		byDevice[settings[i].DeviceID] = &settings[i]
+	}
+
+	now := time.Now()
+	for _, device := range devices {
+		retention := uc.Cameras.Retention(byDevice[device.ID])
+		if retention <= 0 {
+			continue
+		}
+		deleted, err := uc.purge(ctx, device.ID, now.Add(-retention))
+		if err != nil {
+			log.Printf("Frame retention: failed to purge frames of %s: %v", device.ID, err)
+		}
+		if deleted > 0 {
+			log.Printf("Frame retention: deleted %d frames of %s", deleted, device.ID)
+		}
+	}
+}
+
+// purge deletes a camera's frames taken before cutoff in batches. Blobs go first so a failure
+// leaves rows to retry rather than orphaned files.
+func (uc *FrameRetentionUseCase) purge(ctx context.Context, deviceID uuid.UUID, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		images, err := uc.ImageRepo.FindExpired(ctx, deviceID, cutoff, uc.BatchSize)
+		if err != nil || len(images) == 0 {
+			return total, err
+		}
+		ids := make([]uuid.UUID, 0, len(images))
+		for i := range images {
+			if err := uc.Blobs.Delete(ctx, images[i].ThumbnailKey()); err != nil {
+				return total, err
+			}
+			if err := uc.Blobs.Delete(ctx, images[i].StorageKey); err != nil {
+				return total, err
+			}
+			ids = append(ids, images[i].ID)
+		}
+		deleted, err := uc.ImageRepo.Delete(ctx, ids)
+		total += deleted
+		if err != nil || len(images) < uc.BatchSize {
+			return total, err
+		}
+	}
+}
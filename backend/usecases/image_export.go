@@ -0,0 +1,175 @@
+package usecases
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+var ErrUnsupportedExportFormat = errors.New("format must be one of zip, mjpeg or avi")
+
+// ExportFormat describes how an exported clip is packaged and served.
+type ExportFormat struct {
+	ContentType string
+	Extension   string
+}
+
+// ExportFormats lists the supported clip formats by name.
+var ExportFormats = map[string]ExportFormat{
+	"zip":   {ContentType: "application/zip", Extension: "zip"},
+	"mjpeg": {ContentType: "video/x-motion-jpeg", Extension: "mjpeg"},
+	"avi":   {ContentType: "video/x-msvideo", Extension: "avi"},
+}
+
+// exportPageSize is how many frame records are read per query while planning an export.
+const exportPageSize = 500
+
+// ExportQuery selects the frames of a clip: those taken in [Start, End], at least Interval apart.
+type ExportQuery struct {
+	Start    time.Time
+	End      time.Time
+	Interval time.Duration
+}
+
+// PlanExport returns the frames of the device that make up the clip, oldest first. It fails with
+// ErrExportTooLarge before reading any JPEG when the clip would exceed the configured frame limit.
+func (uc *ImageUseCase) PlanExport(ctx context.Context, deviceID uuid.UUID, q ExportQuery) ([]entities.Image, error) {
+	if q.Start.IsZero() || q.End.IsZero() || !q.Start.Before(q.End) {
+		return nil, ErrInvalidTimeRange
+	}
+	if _, err := uc.DeviceRepo.FindByID(ctx, deviceID); err != nil {
+		return nil, err
+	}
+
+	var frames []entities.Image
+	cursor := q.Start
+	for {
+		page, err := uc.ImageRepo.ScanByDevice(ctx, deviceID, cursor, q.End, exportPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range page {
+			if image.Timestamp.Before(cursor) {
+				continue // Within Interval of the previous frame
+			}
+			if len(frames) == uc.Config.ExportMaxFrames {
+				return nil, ErrExportTooLarge
+			}
+			frames = append(frames, image)
+			if q.Interval > 0 {
+				cursor = image.Timestamp.Add(q.Interval)
+			}
+		}
+		if len(page) < exportPageSize {
+			return frames, nil
+		}
+		// Timestamps are stored with microsecond precision; step past the last one read
+		if next := page[len(page)-1].Timestamp.Add(time.Microsecond); cursor.Before(next) {
+			cursor = next
+		}
+	}
+}
+
+// WriteExport writes the frames to w in the named format. Frames whose JPEG has gone missing
+// from the blob store are skipped.
+func (uc *ImageUseCase) WriteExport(ctx context.Context, w io.Writer, frames []entities.Image, format string, fps int) error {
+	switch format {
+	case "zip":
+		return uc.writeZip(ctx, w, frames)
+	case "mjpeg":
+		return uc.eachFrame(ctx, frames, func(_ *entities.Image, data []byte) error {
+			_, err := w.Write(data)
+			return err
+		})
+	case "avi":
+		return uc.writeAVI(ctx, w, frames, fps)
+	default:
+		return ErrUnsupportedExportFormat
+	}
+}
+
+// eachFrame reads the JPEG of every frame in turn, stopping early when ctx is done.
+func (uc *ImageUseCase) eachFrame(ctx context.Context, frames []entities.Image, fn func(image *entities.Image, data []byte) error) error {
+	for i := range frames {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := uc.Blobs.Get(ctx, frames[i].StorageKey)
+		if errors.Is(err, interfaces.ErrNotFound) {
+			log.Printf("Export: frame %s is missing from the blob store", frames[i].ID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(&frames[i], data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeZip stores each JPEG uncompressed (JPEG does not compress further), named by capture time.
+func (uc *ImageUseCase) writeZip(ctx context.Context, w io.Writer, frames []entities.Image) error {
+	zw := zip.NewWriter(w)
+	err := uc.eachFrame(ctx, frames, func(image *entities.Image, data []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     fmt.Sprintf("%s_%s.jpg", image.Timestamp.UTC().Format("20060102T150405.000Z"), image.ID.String()[:8]),
+			Method:   zip.Store,
+			Modified: image.Timestamp,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeAVI builds the AVI in a temporary file, since its header is only complete once every
+// frame has been written, and then copies it to w.
+func (uc *ImageUseCase) writeAVI(ctx context.Context, w io.Writer, frames []entities.Image, fps int) error {
+	tmp, err := os.CreateTemp("", "minesense-export-*.avi")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	width, height := 0, 0
+	if len(frames) > 0 {
+		width, height = frames[0].Width, frames[0].Height
+	}
+	avi, err := uc.Clips.NewClip(tmp, width, height, fps)
+	if err != nil {
+		return err
+	}
+	err = uc.eachFrame(ctx, frames, func(_ *entities.Image, data []byte) error {
+		return avi.WriteFrame(data)
+	})
+	if err != nil {
+		return err
+	}
+	if err := avi.Close(); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, tmp)
+	return err
+}
@@ -0,0 +1,255 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidImage   = errors.New("image is not a valid JPEG")
+	ErrImageTooLarge  = errors.New("image exceeds the maximum frame size")
+	ErrExportTooLarge = errors.New("export exceeds the maximum number of frames; narrow the range or raise the interval")
+)
+
+const (
+	defaultImageListLimit = 100
+	maxImageListLimit     = 1000
+)
+
+// MediaConfig controls frame storage and the signed URLs handed out for stored frames.
+type MediaConfig struct {
+	// MaxFrameBytes bounds a single decoded JPEG frame.
+	MaxFrameBytes int64
+	// URLTTL is how long a signed frame URL stays valid.
+	URLTTL time.Duration
+	// BaseURL makes frame URLs absolute (e.g. https://api.example.com); empty yields relative URLs.
+	BaseURL string
+	// ThumbnailWidth is the width in pixels of generated thumbnails.
+	ThumbnailWidth int
+	// ExportMaxFrames bounds the number of frames in one clip export.
+	ExportMaxFrames int
+}
+
+// ImageUseCase stores camera frames in the blob store, indexes them in the database with their
+// motion score, announces each one to real-time clients by URL and hands it to live video viewers.
+type ImageUseCase struct {
+	ImageRepo  interfaces.ImageRepository
+	DeviceRepo interfaces.DeviceRepository
+	Blobs      interfaces.BlobStore
+	Hub        interfaces.Broadcaster
+	Frames     interfaces.FrameBroadcaster
+	Motion     *MotionUseCase
+	Processor  interfaces.ImageProcessor
+	Signer     interfaces.URLSigner
+	Clips      interfaces.ClipEncoder
+	Config     MediaConfig
+}
+
+func NewImageUseCase(imageRepo interfaces.ImageRepository, deviceRepo interfaces.DeviceRepository, blobs interfaces.BlobStore, hub interfaces.Broadcaster, frames interfaces.FrameBroadcaster, motion *MotionUseCase, processor interfaces.ImageProcessor, signer interfaces.URLSigner, clips interfaces.ClipEncoder, config MediaConfig) *ImageUseCase {
+	if config.MaxFrameBytes <= 0 {
+		config.MaxFrameBytes = 2 << 20
+	}
+	if config.URLTTL <= 0 {
+		config.URLTTL = 24 * time.Hour
+	}
+	if config.ThumbnailWidth <= 0 {
+		config.ThumbnailWidth = 160
+	}
+	if config.ExportMaxFrames <= 0 {
+		config.ExportMaxFrames = 3000
+	}
+	return &ImageUseCase{
+		ImageRepo:  imageRepo,
+		DeviceRepo: deviceRepo,
+		Blobs:      blobs,
+		Hub:        hub,
+		Frames:     frames,
+		Motion:     motion,
+		Processor:  processor,
+		Signer:     signer,
+		Clips:      clips,
+		Config:     config,
+	}
+}
+
+// frameKey spreads frames over per-device, per-day directories.
+func frameKey(image *entities.Image) string {
+	return fmt.Sprintf("frames/%s/%s/%s.jpg", image.DeviceID, image.Timestamp.UTC().Format("2006/01/02"), image.ID)
+}
+
+// StoreFrame validates a JPEG frame, writes it to the blob store, records it, broadcasts its URL
+// and streams it to live viewers.
+func (uc *ImageUseCase) StoreFrame(ctx context.Context, deviceID uuid.UUID, data []byte, takenAt time.Time) (*entities.Image, error) {
+	// 1. Validate the frame
+	if int64(len(data)) > uc.Config.MaxFrameBytes {
+		return nil, ErrImageTooLarge
+	}
+	width, height, err := uc.Processor.Dimensions(data)
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if _, err := uc.DeviceRepo.FindByID(ctx, deviceID); err != nil {
+		return nil, err
+	}
+
+	// 2. Score it against the camera's previous frame, then store the JPEG and its record;
+	// a failed insert must not leave an orphaned blob, and only stored frames become the baseline
+	image := &entities.Image{
+		ID:          uuid.New(),
+		DeviceID:    deviceID,
+		ContentType: "image/jpeg",
+		SizeBytes:   int64(len(data)),
+		Width:       width,
+		Height:      height,
+		Timestamp:   takenAt,
+	}
+	image.StorageKey = frameKey(image)
+	analysis := uc.Motion.Analyze(image, data)
+	if err := uc.Blobs.Put(ctx, image.StorageKey, data); err != nil {
+		return nil, fmt.Errorf("failed to store frame: %w", err)
+	}
+	if err := uc.ImageRepo.Create(ctx, image); err != nil {
+		if delErr := uc.Blobs.Delete(context.Background(), image.StorageKey); delErr != nil {
+			log.Printf("Failed to remove orphaned frame %s: %v", image.StorageKey, delErr)
+		}
+		return nil, err
+	}
+	alerts := uc.Motion.Commit(ctx, image, analysis)
+
+	// 3. Announce the frame by URL, stream the bytes to live viewers, then raise any alerts
+	// so their evidence already includes this frame
+	uc.withURL(image)
+	uc.Hub.BroadcastData(entities.NewImageUpdateEvent(image))
+	uc.Frames.PublishFrame(deviceID, data)
+	uc.Motion.Raise(ctx, alerts)
+	return image, nil
+}
+
+// withURL fills in signed frame and thumbnail URLs that are valid without an Authorization
+// header. The expiry is rounded up to the hour so a frame keeps the same URLs for a while and
+// browsers can cache them.
+func (uc *ImageUseCase) withURL(image *entities.Image) {
+	path := "/api/v1/images/" + image.ID.String()
+	expires := time.Now().Add(uc.Config.URLTTL).Truncate(time.Hour).Add(time.Hour)
+	image.ImageURL = uc.Config.BaseURL + uc.Signer.SignPath(path, expires)
+	image.ThumbnailURL = uc.Config.BaseURL + uc.Signer.SignPath(path+"/thumbnail", expires)
+}
+
+func (uc *ImageUseCase) GetImage(ctx context.Context, id uuid.UUID) (*entities.Image, error) {
+	image, err := uc.ImageRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	uc.withURL(image)
+	return image, nil
+}
+
+// GetImageData returns a frame's record and its JPEG bytes.
+func (uc *ImageUseCase) GetImageData(ctx context.Context, id uuid.UUID) (*entities.Image, []byte, error) {
+	image, err := uc.ImageRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := uc.Blobs.Get(ctx, image.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return image, data, nil
+}
+
+// GetThumbnail returns a frame's record and a scaled-down JPEG of it. Thumbnails are generated on
+// first request and cached in the blob store next to the frame.
+func (uc *ImageUseCase) GetThumbnail(ctx context.Context, id uuid.UUID) (*entities.Image, []byte, error) {
+	image, err := uc.ImageRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	thumb, err := uc.Blobs.Get(ctx, image.ThumbnailKey())
+	if err == nil {
+		return image, thumb, nil
+	}
+	if !errors.Is(err, interfaces.ErrNotFound) {
+		return nil, nil, err
+	}
+
+	data, err := uc.Blobs.Get(ctx, image.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	thumb, err = uc.Processor.Thumbnail(data, uc.Config.ThumbnailWidth)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate thumbnail: %w", err)
+	}
+	if err := uc.Blobs.Put(ctx, image.ThumbnailKey(), thumb); err != nil {
+		log.Printf("Failed to cache thumbnail %s: %v", image.ThumbnailKey(), err)
+	}
+	return image, thumb, nil
+}
+
+// ListImages returns a device's frames in [start, end], newest first, only those with a motion
+// score of at least minMotion when it is positive. The range defaults to the last 24 hours and
+// limit to 100 (at most 1000).
+func (uc *ImageUseCase) ListImages(ctx context.Context, deviceID uuid.UUID, start, end time.Time, minMotion float64, limit int) ([]entities.Image, error) {
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if start.IsZero() {
+		start = end.Add(-24 * time.Hour)
+	}
+	if !start.Before(end) {
+		return nil, ErrInvalidTimeRange
+	}
+	if limit <= 0 {
+		limit = defaultImageListLimit
+	}
+	if limit > maxImageListLimit {
+		limit = maxImageListLimit
+	}
+	var images []entities.Image
+	var err error
+	if minMotion > 0 {
+		images, err = uc.ImageRepo.FindMotion(ctx, deviceID, start, end, minMotion, limit)
+	} else {
+		images, err = uc.ImageRepo.FindByDevice(ctx, deviceID, start, end, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range images {
+		uc.withURL(&images[i])
+	}
+	return images, nil
+}
+
+// LatestFrame returns the JPEG of the device's most recent stored frame, or nil if it has none.
+func (uc *ImageUseCase) LatestFrame(ctx context.Context, deviceID uuid.UUID) ([]byte, error) {
+	if _, err := uc.DeviceRepo.FindByID(ctx, deviceID); err != nil {
+		return nil, err
+	}
+	images, err := uc.ImageRepo.FindByDevice(ctx, deviceID, time.Time{}, time.Now(), 1)
+	if err != nil || len(images) == 0 {
+		return nil, err
+	}
+	return uc.Blobs.Get(ctx, images[0].StorageKey)
+}
+
+// GetLatestImage returns the device's most recent frame.
+func (uc *ImageUseCase) GetLatestImage(ctx context.Context, deviceID uuid.UUID) (*entities.Image, error) {
+	images, err := uc.ImageRepo.FindByDevice(ctx, deviceID, time.Time{}, time.Now(), 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, interfaces.ErrNotFound
+	}
+	uc.withURL(&images[0])
+	return &images[0], nil
+}
@@ -0,0 +1,186 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+// A reading that fails to persist is retried this many times in all, backing off between
+// attempts, so a transient database error does not lose a hazard reading.
+const (
+	processAttempts = 3
+	processBackoff  = 200 * time.Millisecond
+)
+
+var (
+	ErrIngestQueueFull = errors.New("ingestion queue is full")
+	ErrIngestStopped   = errors.New("ingestion pipeline is not running")
+)
+
+// SensorJob is a validated reading waiting to be persisted and evaluated.
+type SensorJob struct {
+	DeviceID   uuid.UUID
+	SensorType string
+	Payload    json.RawMessage
+	ReceivedAt time.Time
+}
+
+// IngestionUseCase decouples the HTTP handler from persistence and hazard evaluation.
+// Jobs are sharded by device ID so each device's readings are processed in order by a single worker,
+// while different devices are processed in parallel.
+type IngestionUseCase struct {
+	SensorUseCase *SensorUseCase
+	Hub           interfaces.Broadcaster
+
+	queues  []chan SensorJob
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	running bool
+
+	processed atomic.Int64
+	failed    atomic.Int64
+	rejected  atomic.Int64
+}
+
+func NewIngestionUseCase(sensorUseCase *SensorUseCase, hub interfaces.Broadcaster, workers, queueSize int) *IngestionUseCase {
+	if workers < 1 {
+		workers = 1
+	}
+	perWorker := queueSize / workers
+	if perWorker < 1 {
+		perWorker = 1
+	}
+
+	queues := make([]chan SensorJob, workers)
+	for i := range queues {
+		queues[i] = make(chan SensorJob, perWorker)
+	}
+
+	return &IngestionUseCase{
+		SensorUseCase: sensorUseCase,
+		Hub:           hub,
+		queues:        queues,
+	}
+}
+
+// Start launches one worker goroutine per queue.
+func (uc *IngestionUseCase) Start() {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if uc.running {
+		return
+	}
+	uc.running = true
+	for _, q := range uc.queues {
+		uc.wg.Add(1)
+		go uc.worker(q)
+	}
+	log.Printf("Ingestion pipeline started with %d workers", len(uc.queues))
+}
+
+// Stop rejects new jobs and waits for queued jobs to drain.
+func (uc *IngestionUseCase) Stop() {
+	uc.mu.Lock()
+	if !uc.running {
+		uc.mu.Unlock()
+		return
+	}
+	uc.running = false
+	for _, q := range uc.queues {
+		close(q)
+	}
+	uc.mu.Unlock()
+
+	uc.wg.Wait()
+	log.Println("Ingestion pipeline stopped")
+}
+
+// Enqueue hands a job to the worker owning its device without blocking.
+func (uc *IngestionUseCase) Enqueue(job SensorJob) error {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	if !uc.running {
+		return ErrIngestStopped
+	}
+
+	select {
+	case uc.queues[uc.shard(job.DeviceID)] <- job:
+		return nil
+	default:
+		uc.rejected.Add(1)
+		return ErrIngestQueueFull
+	}
+}
+
+// QueueDepth returns the number of jobs waiting across all workers.
+func (uc *IngestionUseCase) QueueDepth() int {
+	depth := 0
+	for _, q := range uc.queues {
+		depth += len(q)
+	}
+	return depth
+}
+
+// QueueCapacity returns the total number of jobs the pipeline can buffer.
+func (uc *IngestionUseCase) QueueCapacity() int {
+	capacity := 0
+	for _, q := range uc.queues {
+		capacity += cap(q)
+	}
+	return capacity
+}
+
+func (uc *IngestionUseCase) Processed() int64 { return uc.processed.Load() }
+func (uc *IngestionUseCase) Failed() int64    { return uc.failed.Load() }
+func (uc *IngestionUseCase) Rejected() int64  { return uc.rejected.Load() }
+
+func (uc *IngestionUseCase) shard(deviceID uuid.UUID) int {
+	h := fnv.New32a()
+	h.Write(deviceID[:])
+	return int(h.Sum32() % uint32(len(uc.queues)))
+}
+
+func (uc *IngestionUseCase) worker(queue <-chan SensorJob) {
+	defer uc.wg.Done()
+	for job := range queue {
+		uc.process(job)
+	}
+}
+
+func (uc *IngestionUseCase) process(job SensorJob) {
+	var alerts []*entities.Alert
+	var err error
+	for attempt := 1; ; attempt++ {
+		alerts, err = uc.SensorUseCase.ProcessSensorData(context.Background(), job.DeviceID, job.SensorType, job.Payload, job.ReceivedAt)
+		if err == nil {
+			break
+		}
+		if attempt == processAttempts {
+			uc.failed.Add(1)
+			log.Printf("Failed to process reading for device %s after %d attempts: %v", job.DeviceID, attempt, err)
+			return
+		}
+		log.Printf("Failed to process reading for device %s, retrying: %v", job.DeviceID, err)
+		time.Sleep(processBackoff * time.Duration(attempt))
+	}
+	uc.processed.Add(1)
+
+	// Broadcast to WebSocket clients
+	uc.Hub.BroadcastData(entities.NewSensorUpdateEvent(job.DeviceID, job.SensorType, job.Payload, job.ReceivedAt))
+
+	// Broadcast Alerts if any
+	for _, alert := range alerts {
+		uc.Hub.BroadcastData(entities.NewAlertEvent(alert))
+	}
+}
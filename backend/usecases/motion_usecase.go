@@ -0,0 +1,181 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+// MotionPolicy configures scene change detection on camera frames.
+type MotionPolicy struct {
+	// Threshold is the motion score that counts as motion for cameras without their own.
+	Threshold float64
+	// Frames darker than DarkBrightness, or flatter than FlatContrast (a covered lens), for
+	// ObstructedFrames frames in a row raise a "Camera Obstructed" alert.
+	DarkBrightness   float64
+	FlatContrast     float64
+	ObstructedFrames int
+	// Cooldown is the least time between two motion alerts of one camera.
+	Cooldown time.Duration
+}
+
+// motionState is what the detector remembers about a camera between frames.
+type motionState struct {
+	prev            interfaces.Scene
+	obscuredFrames  int
+	obstructed      bool // An obstruction alert was raised and the view has not cleared since
+	lastMotionAlert time.Time
+}
+
+// MotionUseCase compares each frame with the previous frame of the same camera, scores the
+// change and raises "Restricted Area Motion" and "Camera Obstructed" alerts. State is kept in
+// memory, so the first frame of each camera after a restart is not scored.
+type MotionUseCase struct {
+	AlertRepo  interfaces.AlertRepository
+	CameraRepo interfaces.CameraSettingsRepository
+	Hub        interfaces.Broadcaster
+	Scenes     interfaces.SceneAnalyzer
+	Policy     MotionPolicy
+
+	mu      sync.Mutex
+	cameras map[uuid.UUID]*motionState
+}
+
+func NewMotionUseCase(alertRepo interfaces.AlertRepository, cameraRepo interfaces.CameraSettingsRepository, hub interfaces.Broadcaster, scenes interfaces.SceneAnalyzer, policy MotionPolicy) *MotionUseCase {
+	if policy.Threshold <= 0 {
+		policy.Threshold = 0.1
+	}
+	if policy.ObstructedFrames <= 0 {
+		policy.ObstructedFrames = 3
+	}
+	return &MotionUseCase{
+		AlertRepo:  alertRepo,
+		CameraRepo: cameraRepo,
+		Hub:        hub,
+		Scenes:     scenes,
+		Policy:     policy,
+		cameras:    make(map[uuid.UUID]*motionState),
+	}
+}
+
+// MotionAnalysis is a frame's scene, scored against the camera's previous frame but not yet
+// its baseline.
+type MotionAnalysis struct {
+	scene interfaces.Scene
+}
+
+// Analyze fills in the frame's motion score and brightness. A frame that cannot be analyzed
+// is stored unscored, and nil is returned. The frame only becomes the camera's baseline once it
+// has been stored and passed to Commit, so frames that fail to store do not shift it.
+func (uc *MotionUseCase) Analyze(image *entities.Image, data []byte) *MotionAnalysis {
+	scene, err := uc.Scenes.AnalyzeScene(data)
+	if err != nil {
+		log.Printf("Motion: failed to analyze frame of %s: %v", image.DeviceID, err)
+		return nil
+	}
+	brightness := scene.Brightness()
+	image.Brightness = &brightness
+
+	uc.mu.Lock()
+	if state, ok := uc.cameras[image.DeviceID]; ok && state.prev != nil {
+		score := scene.Difference(state.prev)
+		image.MotionScore = &score
+	}
+	uc.mu.Unlock()
+	return &MotionAnalysis{scene: scene}
+}
+
+// Commit makes a stored frame the camera's baseline and returns the alerts it triggers, to be
+// raised with Raise.
+func (uc *MotionUseCase) Commit(ctx context.Context, image *entities.Image, analysis *MotionAnalysis) []*entities.Alert {
+	if analysis == nil {
+		return nil
+	}
+	scene := analysis.scene
+	obscured := scene.Brightness() < uc.Policy.DarkBrightness || scene.Contrast() < uc.Policy.FlatContrast
+
+	// 1. Remember the frame and track how long the view has been obscured
+	uc.mu.Lock()
+	state, ok := uc.cameras[image.DeviceID]
+	if !ok {
+		state = &motionState{}
+		uc.cameras[image.DeviceID] = state
+	}
+	state.prev = scene
+	raiseObstructed := false
+	if obscured {
+		state.obscuredFrames++
+		if state.obscuredFrames >= uc.Policy.ObstructedFrames && !state.obstructed {
+			state.obstructed, raiseObstructed = true, true
+		}
+	} else {
+		state.obscuredFrames, state.obstructed = 0, false
+	}
+	obscuredFrames := state.obscuredFrames
+	uc.mu.Unlock()
+
+	var alerts []*entities.Alert
+	if raiseObstructed {
+		alerts = append(alerts, &entities.Alert{
+			DeviceID:  image.DeviceID,
+			AlertType: "Camera Obstructed",
+			Severity:  "Warning",
+			Message:   fmt.Sprintf("Camera view dark or blocked for %d frames in a row (brightness %.0f%%). Check the lens and lighting.", obscuredFrames, scene.Brightness()*100),
+		})
+	}
+
+	// 2. Motion only matters on cameras watching a restricted area, and not while the view is obscured
+	if image.MotionScore == nil || obscured {
+		return alerts
+	}
+	settings, err := uc.CameraRepo.FindByDeviceID(ctx, image.DeviceID)
+	if err != nil {
+		if !errors.Is(err, interfaces.ErrNotFound) {
+			log.Printf("Motion: failed to load camera settings of %s: %v", image.DeviceID, err)
+		}
+		return alerts
+	}
+	threshold := uc.Policy.Threshold
+	if settings.MotionThreshold != nil {
+		threshold = *settings.MotionThreshold
+	}
+	if !settings.RestrictedArea || *image.MotionScore < threshold {
+		return alerts
+	}
+
+	uc.mu.Lock()
+	cooledDown := time.Since(state.lastMotionAlert) >= uc.Policy.Cooldown
+	if cooledDown {
+		state.lastMotionAlert = time.Now()
+	}
+	uc.mu.Unlock()
+	if cooledDown {
+		alerts = append(alerts, &entities.Alert{
+			DeviceID:  image.DeviceID,
+			AlertType: "Restricted Area Motion",
+			Severity:  "High",
+			Message:   fmt.Sprintf("Motion detected in restricted area (%.0f%% of the scene changed).", *image.MotionScore*100),
+		})
+	}
+	return alerts
+}
+
+// Raise records the alerts and broadcasts them.
+func (uc *MotionUseCase) Raise(ctx context.Context, alerts []*entities.Alert) {
+	for _, alert := range alerts {
+		alert.CreatedAt = time.Now()
+		if err := uc.AlertRepo.Create(ctx, alert); err != nil {
+			log.Printf("Failed to record %s alert for %s: %v", alert.AlertType, alert.DeviceID, err)
+			continue
+		}
+		uc.Hub.BroadcastData(entities.NewAlertEvent(alert))
+	}
+}
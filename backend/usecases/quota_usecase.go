@@ -0,0 +1,134 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+// quotaAlertCooldown stops a misbehaving device from flooding the alert log.
+const quotaAlertCooldown = 10 * time.Minute
+
+type deviceOverage struct {
+	windowStart time.Time
+	drops       int
+	lastAlert   time.Time
+	lastDrop    time.Time
+}
+
+// QuotaUseCase enforces per-device ingestion budgets and raises an alert
+// when a device keeps exceeding its budget (e.g. firmware stuck in a tight loop).
+type QuotaUseCase struct {
+	TelemetryLimiter interfaces.RateLimiter
+	FrameLimiter     interfaces.RateLimiter
+	AlertRepo        interfaces.AlertRepository
+	Hub              interfaces.Broadcaster
+
+	// AlertThreshold drops within AlertWindow trigger a "Rate Limit Exceeded" alert.
+	AlertThreshold int
+	AlertWindow    time.Duration
+
+	mu        sync.Mutex
+	overages  map[uuid.UUID]*deviceOverage
+	lastSweep time.Time
+}
+
+func NewQuotaUseCase(telemetryLimiter, frameLimiter interfaces.RateLimiter, alertRepo interfaces.AlertRepository, hub interfaces.Broadcaster, alertThreshold int, alertWindow time.Duration) *QuotaUseCase {
+	return &QuotaUseCase{
+		TelemetryLimiter: telemetryLimiter,
+		FrameLimiter:     frameLimiter,
+		AlertRepo:        alertRepo,
+		Hub:              hub,
+		AlertThreshold:   alertThreshold,
+		AlertWindow:      alertWindow,
+		overages:         make(map[uuid.UUID]*deviceOverage),
+		lastSweep:        time.Now(),
+	}
+}
+
+// AllowTelemetry checks the device's sensor upload budget.
+func (uc *QuotaUseCase) AllowTelemetry(deviceID uuid.UUID) (bool, time.Duration) {
+	return uc.allow(uc.TelemetryLimiter, deviceID)
+}
+
+// AllowFrame checks the device's camera frame budget.
+func (uc *QuotaUseCase) AllowFrame(deviceID uuid.UUID) (bool, time.Duration) {
+	return uc.allow(uc.FrameLimiter, deviceID)
+}
+
+func (uc *QuotaUseCase) allow(limiter interfaces.RateLimiter, deviceID uuid.UUID) (bool, time.Duration) {
+	ok, wait := limiter.Allow(deviceID.String())
+	if !ok {
+		uc.recordDrop(deviceID)
+	}
+	return ok, wait
+}
+
+func (uc *QuotaUseCase) recordDrop(deviceID uuid.UUID) {
+	if uc.AlertThreshold <= 0 {
+		return
+	}
+
+	now := time.Now()
+	uc.mu.Lock()
+	uc.sweep(now)
+	o, ok := uc.overages[deviceID]
+	if !ok || now.Sub(o.windowStart) > uc.AlertWindow {
+		if !ok {
+			o = &deviceOverage{}
+			uc.overages[deviceID] = o
+		}
+		o.windowStart = now
+		o.drops = 0
+	}
+	o.drops++
+	o.lastDrop = now
+
+	shouldAlert := o.drops >= uc.AlertThreshold && now.Sub(o.lastAlert) > quotaAlertCooldown
+	if shouldAlert {
+		o.lastAlert = now
+	}
+	drops := o.drops
+	uc.mu.Unlock()
+
+	if shouldAlert {
+		go uc.raiseAlert(deviceID, drops)
+	}
+}
+
+// sweep forgets devices that stopped exceeding their budget long enough for both their drop
+// window and alert cooldown to have expired. Callers hold uc.mu.
+func (uc *QuotaUseCase) sweep(now time.Time) {
+	idle := max(uc.AlertWindow, quotaAlertCooldown)
+	if now.Sub(uc.lastSweep) < idle {
+		return
+	}
+	for id, o := range uc.overages {
+		if now.Sub(o.lastDrop) > idle {
+			delete(uc.overages, id)
+		}
+	}
+	uc.lastSweep = now
+}
+
+func (uc *QuotaUseCase) raiseAlert(deviceID uuid.UUID, drops int) {
+	alert := &entities.Alert{
+		DeviceID:  deviceID,
+		AlertType: "Rate Limit Exceeded",
+		Severity:  "Warning",
+		Message:   fmt.Sprintf("Device exceeded its ingestion budget (%d requests dropped within %s). Check firmware upload interval.", drops, uc.AlertWindow),
+		CreatedAt: time.Now(),
+	}
+	if err := uc.AlertRepo.Create(context.Background(), alert); err != nil {
+		log.Printf("Failed to create rate limit alert for device %s: %v", deviceID, err)
+		return
+	}
+	uc.Hub.BroadcastData(entities.NewAlertEvent(alert))
+}
@@ -0,0 +1,221 @@
+package usecases
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"minesense-backend/domain/entities"
+	"minesense-backend/domain/interfaces"
+)
+
+// rollupLag keeps the rollup job behind real time so readings still in the ingestion queue are not missed.
+const rollupLag = 2 * time.Minute
+
+// MaxBackdate is how far before its receipt a reading may be stamped with the device's own
+// capture time. Older readings would land in minutes that are already rolled up, so half the
+// rollup lag is left for the ingestion queue.
+const MaxBackdate = rollupLag / 2
+
+// maxRollupSpan bounds how much raw history one pass rolls up, so catching up after downtime
+// (or on first deploy) is spread across several passes instead of one huge statement.
+const maxRollupSpan = 6 * time.Hour
+
+// RetentionPolicy controls how long each resolution of sensor data is kept. A zero duration keeps data forever.
+type RetentionPolicy struct {
+	RawRetention    time.Duration
+	MinuteRetention time.Duration
+	HourRetention   time.Duration
+	AlertRetention  time.Duration
+	// PartitionsAhead is how many future monthly partitions are kept ready.
+	PartitionsAhead int
+	Interval        time.Duration
+	BatchSize       int
+}
+
+// RetentionUseCase periodically rolls raw readings up into minute/hour tables, keeps monthly
+// partitions ahead of time and deletes data that has aged out of its retention window.
+type RetentionUseCase struct {
+	RollupRepo    interfaces.SensorRollupRepository
+	PartitionRepo interfaces.PartitionRepository
+	AlertRepo     interfaces.AlertRepository
+	Policy        RetentionPolicy
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewRetentionUseCase(rollupRepo interfaces.SensorRollupRepository, partitionRepo interfaces.PartitionRepository, alertRepo interfaces.AlertRepository, policy RetentionPolicy) *RetentionUseCase {
+	if policy.Interval <= 0 {
+		policy.Interval = 5 * time.Minute
+	}
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = 5000
+	}
+	if policy.PartitionsAhead < 1 {
+		policy.PartitionsAhead = 1
+	}
+	return &RetentionUseCase{
+		RollupRepo:    rollupRepo,
+		PartitionRepo: partitionRepo,
+		AlertRepo:     alertRepo,
+		Policy:        policy,
+		done:          make(chan struct{}),
+	}
+}
+
+// Start runs the compaction job in the background until Stop is called.
+func (uc *RetentionUseCase) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	uc.cancel = cancel
+	go func() {
+		defer close(uc.done)
+		ticker := time.NewTicker(uc.Policy.Interval)
+		defer ticker.Stop()
+
+		uc.RunOnce(ctx)
+		for {
+			select {
+			case <-ticker.C:
+				uc.RunOnce(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	log.Printf("Retention job started (every %s)", uc.Policy.Interval)
+}
+
+func (uc *RetentionUseCase) Stop() {
+	uc.once.Do(func() {
+		if uc.cancel == nil {
+			return
+		}
+		// Cancelling the context aborts any in-flight query so shutdown is not held up by a long purge
+		uc.cancel()
+		<-uc.done
+	})
+}
+
+// RunOnce performs a single rollup and cleanup pass. Errors are logged; the next pass retries.
+func (uc *RetentionUseCase) RunOnce(ctx context.Context) {
+	now := time.Now().UTC()
+
+	// 0. Keep upcoming monthly partitions in place so inserts never land in the default partition
+	for _, table := range []string{"sensor_readings", "alerts"} {
+		if err := uc.PartitionRepo.EnsureMonthlyPartitions(ctx, table, now, uc.Policy.PartitionsAhead); err != nil {
+			log.Printf("Retention: failed to create partitions for %s: %v", table, err)
+		}
+	}
+
+	// 1. Raw -> minute rollups, resuming from the newest minute already rolled up
+	// (re-rolling that minute is harmless because rollups are upserts)
+	minuteTo := now.Add(-rollupLag).Truncate(time.Minute)
+	minuteFrom, err := uc.resumePoint(ctx, entities.RollupMinute, uc.RollupRepo.OldestRawTimestamp)
+	if err != nil {
+		log.Printf("Retention: failed to find minute rollup resume point: %v", err)
+		return
+	}
+	if !minuteFrom.IsZero() {
+		minuteFrom = minuteFrom.Truncate(time.Minute)
+		if limit := minuteFrom.Add(maxRollupSpan); limit.Before(minuteTo) {
+			minuteTo = limit
+		}
+		if minuteFrom.Before(minuteTo) {
+			rows, err := uc.RollupRepo.RollupRaw(ctx, minuteFrom, minuteTo)
+			if err != nil {
+				log.Printf("Retention: minute rollup failed: %v", err)
+				return
+			}
+			log.Printf("Retention: rolled up %d minute buckets", rows)
+		}
+	}
+
+	// 2. Minute -> hour rollups, only for hours whose minutes are complete
+	hourTo := minuteTo.Truncate(time.Hour)
+	hourFrom, err := uc.resumePoint(ctx, entities.RollupHour, func(ctx context.Context, after time.Time) (time.Time, error) {
+		return uc.RollupRepo.OldestRollup(ctx, entities.RollupMinute, after)
+	})
+	if err != nil {
+		log.Printf("Retention: failed to find hour rollup resume point: %v", err)
+		return
+	}
+	if !hourFrom.IsZero() {
+		hourFrom = hourFrom.Truncate(time.Hour)
+		if hourFrom.Before(hourTo) {
+			rows, err := uc.RollupRepo.RollupMinutes(ctx, hourFrom, hourTo)
+			if err != nil {
+				log.Printf("Retention: hour rollup failed: %v", err)
+				return
+			}
+			log.Printf("Retention: rolled up %d hour buckets", rows)
+		}
+	}
+
+	// 3. Delete aged-out data. Raw data is only removed once it has been rolled up.
+	if uc.Policy.RawRetention > 0 {
+		cutoff := now.Add(-uc.Policy.RawRetention)
+		if minuteTo.Before(cutoff) {
+			cutoff = minuteTo
+		}
+		// Whole expired months are dropped as partitions; the rest is deleted in batches
+		uc.dropPartitions(ctx, "sensor_readings", cutoff)
+		uc.purge("raw readings", cutoff, func(c time.Time) (int64, error) {
+			return uc.RollupRepo.DeleteRawBefore(ctx, c, uc.Policy.BatchSize)
+		})
+	}
+	if uc.Policy.AlertRetention > 0 {
+		cutoff := now.Add(-uc.Policy.AlertRetention)
+		uc.dropPartitions(ctx, "alerts", cutoff)
+		uc.purge("alerts", cutoff, func(c time.Time) (int64, error) {
+			return uc.AlertRepo.DeleteBefore(ctx, c, uc.Policy.BatchSize)
+		})
+	}
+	if uc.Policy.MinuteRetention > 0 {
+		cutoff := now.Add(-uc.Policy.MinuteRetention)
+		if hourTo.Before(cutoff) {
+			cutoff = hourTo
+		}
+		uc.purge("minute rollups", cutoff, func(c time.Time) (int64, error) {
+			return uc.RollupRepo.DeleteRollupsBefore(ctx, entities.RollupMinute, c, uc.Policy.BatchSize)
+		})
+	}
+	if uc.Policy.HourRetention > 0 {
+		uc.purge("hour rollups", now.Add(-uc.Policy.HourRetention), func(c time.Time) (int64, error) {
+			return uc.RollupRepo.DeleteRollupsBefore(ctx, entities.RollupHour, c, uc.Policy.BatchSize)
+		})
+	}
+}
+
+// resumePoint returns where the next rollup at the given resolution should start: the latest
+// bucket already produced, skipping forward over gaps to the oldest source data after it.
+func (uc *RetentionUseCase) resumePoint(ctx context.Context, resolution string, oldestSource func(ctx context.Context, after time.Time) (time.Time, error)) (time.Time, error) {
+	watermark, err := uc.RollupRepo.Watermark(ctx, resolution)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return oldestSource(ctx, watermark)
+}
+
+func (uc *RetentionUseCase) dropPartitions(ctx context.Context, table string, cutoff time.Time) {
+	dropped, err := uc.PartitionRepo.DropPartitionsBefore(ctx, table, cutoff)
+	if err != nil {
+		log.Printf("Retention: failed to drop %s partitions older than %s: %v", table, cutoff.Format(time.RFC3339), err)
+	}
+	for _, name := range dropped {
+		log.Printf("Retention: dropped partition %s", name)
+	}
+}
+
+func (uc *RetentionUseCase) purge(what string, cutoff time.Time, del func(time.Time) (int64, error)) {
+	deleted, err := del(cutoff)
+	if err != nil {
+		log.Printf("Retention: failed to delete %s older than %s: %v", what, cutoff.Format(time.RFC3339), err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Retention: deleted %d %s older than %s", deleted, what, cutoff.Format(time.RFC3339))
+	}
+}
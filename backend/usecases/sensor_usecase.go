@@ -1,35 +1,72 @@
 package usecases
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"math"
 	"minesense-backend/domain/entities"
 	"minesense-backend/domain/interfaces"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// AggregationBuckets are the supported bucket widths for sensor history aggregation.
+var AggregationBuckets = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// maxAggregateBuckets caps how many buckets a single query may produce per device and metric.
+const maxAggregateBuckets = 5000
+
+var (
+	ErrInvalidBucket    = errors.New("invalid bucket, expected one of 1m, 5m, 1h, 1d")
+	ErrNoDevices        = errors.New("no devices selected")
+	ErrRangeTooLarge    = errors.New("time range too large for bucket size")
+	ErrInvalidTimeRange = errors.New("start must be before end")
+	ErrInvalidStartTime = errors.New("invalid start time, expected RFC3339")
+	ErrInvalidEndTime   = errors.New("invalid end time, expected RFC3339")
+)
+
+// Ranges longer than these are served from rollup tables instead of raw readings.
+const (
+	minuteRollupRange = 24 * time.Hour
+	hourRollupRange   = 7 * 24 * time.Hour
+)
+
 type SensorUseCase struct {
 	SensorRepo interfaces.SensorRepository
 	AlertRepo  interfaces.AlertRepository
+	RollupRepo interfaces.SensorRollupRepository
+	Retention  RetentionPolicy
 }
 
-func NewSensorUseCase(sensorRepo interfaces.SensorRepository, alertRepo interfaces.AlertRepository) *SensorUseCase {
+func NewSensorUseCase(sensorRepo interfaces.SensorRepository, alertRepo interfaces.AlertRepository, rollupRepo interfaces.SensorRollupRepository, retention RetentionPolicy) *SensorUseCase {
 	return &SensorUseCase{
 		SensorRepo: sensorRepo,
 		AlertRepo:  alertRepo,
+		RollupRepo: rollupRepo,
+		Retention:  retention,
 	}
 }
 
-func (uc *SensorUseCase) ProcessSensorData(deviceID uuid.UUID, sensorType string, payload json.RawMessage) ([]*entities.Alert, error) {
+func (uc *SensorUseCase) ProcessSensorData(ctx context.Context, deviceID uuid.UUID, sensorType string, payload json.RawMessage, timestamp time.Time) ([]*entities.Alert, error) {
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
 	reading := &entities.SensorReading{
 		DeviceID:   deviceID,
 		SensorType: sensorType,
 		Payload:    payload,
-		Timestamp:  time.Now(),
+		Timestamp:  timestamp,
 	}
 
-	if err := uc.SensorRepo.Create(reading); err != nil {
+	if err := uc.SensorRepo.Create(ctx, reading); err != nil {
 		return nil, err
 	}
 
@@ -37,21 +74,255 @@ func (uc *SensorUseCase) ProcessSensorData(deviceID uuid.UUID, sensorType string
 	var alerts []*entities.Alert
 	var data map[string]interface{}
 	if err := json.Unmarshal(payload, &data); err == nil {
-		alerts = uc.checkHazards(deviceID, sensorType, data)
+		alerts = uc.checkHazards(ctx, deviceID, sensorType, data)
 	}
 
 	return alerts, nil
 }
 
-func (uc *SensorUseCase) GetLatest(deviceID uuid.UUID) (*entities.SensorReading, error) {
-	return uc.SensorRepo.GetLatestByDeviceID(deviceID)
+func (uc *SensorUseCase) GetLatest(ctx context.Context, deviceID uuid.UUID) (*entities.SensorReading, error) {
+	return uc.SensorRepo.GetLatestByDeviceID(ctx, deviceID)
+}
+
+// GetHistory returns the device's readings between start and end (RFC3339, both optional), oldest
+// first. The part of the range older than raw retention is served from rollups, as one reading
+// per rollup bucket holding each metric's average.
+func (uc *SensorUseCase) GetHistory(ctx context.Context, deviceID uuid.UUID, start, end string) ([]entities.SensorReading, error) {
+	if start == "" || uc.Retention.RawRetention <= 0 || uc.RollupRepo == nil {
+		return uc.SensorRepo.GetHistory(ctx, deviceID, start, end)
+	}
+	from, err := time.Parse(time.RFC3339Nano, start)
+	if err != nil {
+		return nil, ErrInvalidStartTime
+	}
+	var to time.Time
+	if end != "" {
+		if to, err = time.Parse(time.RFC3339Nano, end); err != nil {
+			return nil, ErrInvalidEndTime
+		}
+	}
+	// Raw readings are only deleted once rolled up, so rollups cover everything before rawFrom
+	rawFrom := time.Now().Add(-uc.Retention.RawRetention).Truncate(time.Minute)
+	if !from.Before(rawFrom) {
+		return uc.SensorRepo.GetHistory(ctx, deviceID, start, end)
+	}
+
+	olderTo := rawFrom.Add(-time.Nanosecond)
+	if !to.IsZero() && to.Before(olderTo) {
+		olderTo = to
+	}
+	readings, err := uc.rollupHistory(ctx, deviceID, from, olderTo)
+	if err != nil {
+		return nil, err
+	}
+	if !to.IsZero() && to.Before(rawFrom) {
+		return readings, nil
+	}
+	recent, err := uc.SensorRepo.GetHistory(ctx, deviceID, rawFrom.UTC().Format(time.RFC3339Nano), end)
+	if err != nil {
+		return nil, err
+	}
+	return append(readings, recent...), nil
+}
+
+// rollupHistory turns the device's rollups in [from, to] into readings with sensor type
+// "rollup_1m" or "rollup_1h", using hour rollups where minute rollups have expired.
+func (uc *SensorUseCase) rollupHistory(ctx context.Context, deviceID uuid.UUID, from, to time.Time) ([]entities.SensorReading, error) {
+	resolution := entities.RollupMinute
+	if uc.Retention.MinuteRetention > 0 && from.Before(time.Now().Add(-uc.Retention.MinuteRetention)) {
+		resolution = entities.RollupHour
+	}
+	aggregates, err := uc.RollupRepo.Aggregate(ctx, resolution, entities.SensorAggregateQuery{
+		DeviceIDs: []uuid.UUID{deviceID},
+		Bucket:    rollupWidths[resolution],
+		Start:     from,
+		End:       to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []time.Time
+	payloads := map[time.Time]map[string]float64{}
+	for _, a := range aggregates {
+		bucket := a.Bucket.UTC()
+		if _, ok := payloads[bucket]; !ok {
+			buckets = append(buckets, bucket)
+			payloads[bucket] = map[string]float64{}
+		}
+		payloads[bucket][a.Metric] = a.Avg
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Before(buckets[j]) })
+
+	readings := make([]entities.SensorReading, 0, len(buckets))
+	for _, bucket := range buckets {
+		payload, err := json.Marshal(payloads[bucket])
+		if err != nil {
+			return nil, err
+		}
+		readings = append(readings, entities.SensorReading{
+			DeviceID:   deviceID,
+			SensorType: "rollup_" + resolution,
+			Payload:    payload,
+			Timestamp:  bucket,
+		})
+	}
+	return readings, nil
+}
+
+// Aggregate returns per-bucket statistics for the given devices. Start defaults to 24 hours before End,
+// and End defaults to now.
+func (uc *SensorUseCase) Aggregate(ctx context.Context, deviceIDs []uuid.UUID, bucket string, start, end time.Time, metrics []string) ([]entities.SensorAggregate, error) {
+	width, ok := AggregationBuckets[bucket]
+	if !ok {
+		return nil, ErrInvalidBucket
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if start.IsZero() {
+		start = end.Add(-24 * time.Hour)
+	}
+	if !start.Before(end) {
+		return nil, ErrInvalidTimeRange
+	}
+	if end.Sub(start)/width > maxAggregateBuckets {
+		return nil, ErrRangeTooLarge
+	}
+	if len(deviceIDs) == 0 {
+		return nil, ErrNoDevices
+	}
+
+	query := entities.SensorAggregateQuery{
+		DeviceIDs: deviceIDs,
+		Bucket:    width,
+		Start:     start,
+		End:       end,
+		Metrics:   metrics,
+	}
+	if resolution := uc.rollupSource(width, start, end); resolution != "" {
+		return uc.aggregateRollups(ctx, resolution, query)
+	}
+	return uc.SensorRepo.Aggregate(ctx, query)
+}
+
+// aggregateRollups answers a query from rollups of the given resolution. Rollups lag behind
+// real time, so whatever is newer than them is read from the next finer source (hour rollups,
+// then minute rollups, then raw readings) and merged in, and results still reach up to End.
+func (uc *SensorUseCase) aggregateRollups(ctx context.Context, resolution string, query entities.SensorAggregateQuery) ([]entities.SensorAggregate, error) {
+	sources := []string{entities.RollupMinute}
+	if resolution == entities.RollupHour {
+		sources = []string{entities.RollupHour, entities.RollupMinute}
+	}
+
+	var parts [][]entities.SensorAggregate
+	from := query.Start
+	for _, source := range sources {
+		watermark, err := uc.RollupRepo.Watermark(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		if watermark.IsZero() {
+			continue
+		}
+		// The newest bucket is complete; anything from the next one on is not rolled up yet
+		rolledTo := watermark.Add(rollupWidths[source])
+		if !from.Before(rolledTo) {
+			continue
+		}
+		part := query
+		part.Start, part.End = from, rolledTo.Add(-time.Nanosecond)
+		if query.End.Before(rolledTo) {
+			part.End = query.End
+		}
+		aggregates, err := uc.RollupRepo.Aggregate(ctx, source, part)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, aggregates)
+		if from = rolledTo; !from.Before(query.End) {
+			return mergeAggregates(parts), nil
+		}
+	}
+
+	part := query
+	part.Start = from
+	aggregates, err := uc.SensorRepo.Aggregate(ctx, part)
+	if err != nil {
+		return nil, err
+	}
+	return mergeAggregates(append(parts, aggregates)), nil
+}
+
+// rollupWidths is the bucket width of each rollup resolution.
+var rollupWidths = map[string]time.Duration{
+	entities.RollupMinute: time.Minute,
+	entities.RollupHour:   time.Hour,
+}
+
+// mergeAggregates combines aggregates of consecutive time ranges, oldest first. A bucket that
+// straddles two ranges is folded into one; its last value comes from the newer range.
+func mergeAggregates(parts [][]entities.SensorAggregate) []entities.SensorAggregate {
+	type key struct {
+		device uuid.UUID
+		bucket time.Time
+		metric string
+	}
+	index := map[key]int{}
+	merged := []entities.SensorAggregate{}
+	for _, part := range parts {
+		for _, a := range part {
+			k := key{a.DeviceID, a.Bucket.UTC(), a.Metric}
+			i, ok := index[k]
+			if !ok {
+				index[k] = len(merged)
+				merged = append(merged, a)
+				continue
+			}
+			m := &merged[i]
+			count := m.Count + a.Count
+			if count > 0 {
+				m.Avg = (m.Avg*float64(m.Count) + a.Avg*float64(a.Count)) / float64(count)
+			}
+			m.Min = math.Min(m.Min, a.Min)
+			m.Max = math.Max(m.Max, a.Max)
+			m.Last, m.Count = a.Last, count
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		if !merged[i].Bucket.Equal(merged[j].Bucket) {
+			return merged[i].Bucket.Before(merged[j].Bucket)
+		}
+		if merged[i].DeviceID != merged[j].DeviceID {
+			return merged[i].DeviceID.String() < merged[j].DeviceID.String()
+		}
+		return merged[i].Metric < merged[j].Metric
+	})
+	return merged
 }
 
-func (uc *SensorUseCase) GetHistory(deviceID uuid.UUID, start, end string) ([]entities.SensorReading, error) {
-	return uc.SensorRepo.GetHistory(deviceID, start, end)
+// rollupSource picks the rollup resolution to answer an aggregate query from, or "" for raw readings.
+// Long ranges, or ranges reaching past the raw retention window, are routed to rollups.
+func (uc *SensorUseCase) rollupSource(width time.Duration, start, end time.Time) string {
+	if uc.RollupRepo == nil {
+		return ""
+	}
+	now := time.Now()
+	span := end.Sub(start)
+	rawExpired := uc.Retention.RawRetention > 0 && start.Before(now.Add(-uc.Retention.RawRetention))
+	minuteExpired := uc.Retention.MinuteRetention > 0 && start.Before(now.Add(-uc.Retention.MinuteRetention))
+
+	switch {
+	case width >= time.Hour && (minuteExpired || span > hourRollupRange):
+		return entities.RollupHour
+	case rawExpired || span > minuteRollupRange:
+		return entities.RollupMinute
+	default:
+		return ""
+	}
 }
 
-func (uc *SensorUseCase) checkHazards(deviceID uuid.UUID, sensorType string, data map[string]interface{}) []*entities.Alert {
+func (uc *SensorUseCase) checkHazards(ctx context.Context, deviceID uuid.UUID, sensorType string, data map[string]interface{}) []*entities.Alert {
 	var alerts []*entities.Alert
 
 	// Combined Telemetry or Individual Checks
@@ -61,7 +332,7 @@ func (uc *SensorUseCase) checkHazards(deviceID uuid.UUID, sensorType string, dat
 	// Threshold: > 700 PPM -> Critical
 	if val, ok := data["gas"].(float64); ok {
 		if val > 700 {
-			if alert := uc.createAlert(deviceID, "Gas Hazard", "Critical", "Dangerous Gas Levels (>700 PPM) detected! Evacuate!"); alert != nil {
+			if alert := uc.createAlert(ctx, deviceID, "Gas Hazard", "Critical", "Dangerous Gas Levels (>700 PPM) detected! Evacuate!"); alert != nil {
 				alerts = append(alerts, alert)
 			}
 		}
@@ -71,11 +342,11 @@ func (uc *SensorUseCase) checkHazards(deviceID uuid.UUID, sensorType string, dat
 	// Thresholds: > 25°C (Critical), > 24°C (Caution)
 	if val, ok := data["temp"].(float64); ok {
 		if val > 25 {
-			if alert := uc.createAlert(deviceID, "Heat Stress", "Critical", "Critical Heat (>25°C)! Mandatory removal from area."); alert != nil {
+			if alert := uc.createAlert(ctx, deviceID, "Heat Stress", "Critical", "Critical Heat (>25°C)! Mandatory removal from area."); alert != nil {
 				alerts = append(alerts, alert)
 			}
 		} else if val > 24 {
-			if alert := uc.createAlert(deviceID, "Heat Stress", "Warning", "High Heat (>24°C). Hydration and rest suggested."); alert != nil {
+			if alert := uc.createAlert(ctx, deviceID, "Heat Stress", "Warning", "High Heat (>24°C). Hydration and rest suggested."); alert != nil {
 				alerts = append(alerts, alert)
 			}
 		}
@@ -84,7 +355,7 @@ func (uc *SensorUseCase) checkHazards(deviceID uuid.UUID, sensorType string, dat
 	// 3. Fall Detection (MPU-6050)
 	// Boolean flag from device
 	if val, ok := data["fall"].(bool); ok && val {
-		if alert := uc.createAlert(deviceID, "Man-Down", "Critical", "Fall detected! Man-down event initiated."); alert != nil {
+		if alert := uc.createAlert(ctx, deviceID, "Man-Down", "Critical", "Fall detected! Man-down event initiated."); alert != nil {
 			alerts = append(alerts, alert)
 		}
 	}
@@ -92,7 +363,7 @@ func (uc *SensorUseCase) checkHazards(deviceID uuid.UUID, sensorType string, dat
 	// 4. Structural/Vibration (Piezo)
 	// Assuming "vibration" key or similar if sent
 	if val, ok := data["vibration"].(float64); ok && val > 500 { // Threshold example, adjust as needed
-		if alert := uc.createAlert(deviceID, "Structural Warning", "High", "High-frequency vibration detected!"); alert != nil {
+		if alert := uc.createAlert(ctx, deviceID, "Structural Warning", "High", "High-frequency vibration detected!"); alert != nil {
 			alerts = append(alerts, alert)
 		}
 	}
@@ -100,7 +371,7 @@ func (uc *SensorUseCase) checkHazards(deviceID uuid.UUID, sensorType string, dat
 	return alerts
 }
 
-func (uc *SensorUseCase) createAlert(deviceID uuid.UUID, alertType, severity, message string) *entities.Alert {
+func (uc *SensorUseCase) createAlert(ctx context.Context, deviceID uuid.UUID, alertType, severity, message string) *entities.Alert {
 	alert := &entities.Alert{
 		DeviceID:  deviceID,
 		AlertType: alertType,
@@ -109,12 +380,12 @@ func (uc *SensorUseCase) createAlert(deviceID uuid.UUID, alertType, severity, me
 		CreatedAt: time.Now(),
 	}
 	// Log error if alert creation fails, but don't stop flow
-	if err := uc.AlertRepo.Create(alert); err != nil {
+	if err := uc.AlertRepo.Create(ctx, alert); err != nil {
 		return nil
 	}
 	return alert
 }
 
-func (uc *SensorUseCase) GetReadingsByDevice(deviceID uuid.UUID) ([]entities.SensorReading, error) {
-	return uc.SensorRepo.FindByDeviceID(deviceID)
+func (uc *SensorUseCase) GetReadingsByDevice(ctx context.Context, deviceID uuid.UUID) ([]entities.SensorReading, error) {
+	return uc.SensorRepo.FindByDeviceID(ctx, deviceID)
 }
@@ -1,42 +1,211 @@
 package usecases
 
 import (
+	"context"
 	"errors"
 	"minesense-backend/domain/entities"
 	"minesense-backend/domain/interfaces"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrRegistrationClosed = errors.New("registration is closed; ask an Admin to create your account")
+	ErrInvalidRole        = errors.New("role must be one of Admin, Supervisor or Worker")
+	ErrWeakPassword       = errors.New("password must be at least 8 characters")
+	ErrLastAdmin          = errors.New("at least one enabled Admin must remain")
+	ErrAccountDisabled    = errors.New("account is disabled")
+)
+
+const minPasswordLength = 8
+
+// accountCacheTTL bounds how long a change made on another instance (disabling a user, changing
+// a role) takes to reach tokens checked here; changes made through this instance apply at once.
+const accountCacheTTL = 30 * time.Second
+
+type cachedAccount struct {
+	role    string
+	active  bool
+	expires time.Time
+}
+
 type UserUseCase struct {
 	UserRepo interfaces.UserRepository
+
+	mu       sync.Mutex
+	accounts map[uuid.UUID]cachedAccount
 }
 
 func NewUserUseCase(userRepo interfaces.UserRepository) *UserUseCase {
-	return &UserUseCase{UserRepo: userRepo}
+	return &UserUseCase{UserRepo: userRepo, accounts: make(map[uuid.UUID]cachedAccount)}
 }
 
-func (uc *UserUseCase) Register(username, password, role string) error {
+func (uc *UserUseCase) newUser(username, password, role string) (*entities.User, error) {
+	if !entities.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if len(password) < minPasswordLength {
+		return nil, ErrWeakPassword
+	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	user := &entities.User{
+	return &entities.User{
 		Username:  username,
 		Password:  string(hashedPassword),
 		Role:      role,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// Register creates the first account, which becomes an Admin. Once any account exists,
+// registration is closed and accounts are created by Admins with CreateUser. The repository
+// guarantees only one first account even when several instances register at once.
+func (uc *UserUseCase) Register(ctx context.Context, username, password string) (*entities.User, error) {
+	count, err := uc.UserRepo.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrRegistrationClosed
+	}
+	user, err := uc.newUser(username, password, entities.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	created, err := uc.UserRepo.CreateFirst(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrRegistrationClosed
+	}
+	return user, nil
+}
+
+// Bootstrap creates the initial Admin on a fresh install, reporting whether it did.
+func (uc *UserUseCase) Bootstrap(ctx context.Context, username, password string) (bool, error) {
+	_, err := uc.Register(ctx, username, password)
+	if errors.Is(err, ErrRegistrationClosed) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreateUser creates an account with the given role on an Admin's behalf.
+func (uc *UserUseCase) CreateUser(ctx context.Context, username, password, role string) (*entities.User, error) {
+	user, err := uc.newUser(username, password, role)
+	if err != nil {
+		return nil, err
+	}
+	return user, uc.UserRepo.Create(ctx, user)
+}
+
+func (uc *UserUseCase) ListUsers(ctx context.Context) ([]entities.User, error) {
+	return uc.UserRepo.FindAll(ctx)
+}
+
+// saveKeepingAdmin saves the user, failing with ErrLastAdmin if that would leave no enabled
+// Admin. The repository checks and saves atomically, so concurrent changes on several instances
+// cannot each remove a different last Admin.
+func (uc *UserUseCase) saveKeepingAdmin(ctx context.Context, user *entities.User) error {
+	saved, err := uc.UserRepo.UpdateKeepingAdmin(ctx, user)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		return ErrLastAdmin
+	}
+	uc.forget(user.ID)
+	return nil
+}
+
+// UpdateRole changes a user's role. It takes effect on the user's existing tokens too.
+func (uc *UserUseCase) UpdateRole(ctx context.Context, id uuid.UUID, role string) (*entities.User, error) {
+	if !entities.ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	user, err := uc.UserRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	if err := uc.saveKeepingAdmin(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetDisabled disables or re-enables a user. A disabled user can neither log in nor use
+// tokens issued earlier.
+func (uc *UserUseCase) SetDisabled(ctx context.Context, id uuid.UUID, disabled bool) (*entities.User, error) {
+	user, err := uc.UserRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user.Disabled = disabled
+	user.UpdatedAt = time.Now()
+	if err := uc.saveKeepingAdmin(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteUser removes a user; devices it supervised are left without a supervisor.
+func (uc *UserUseCase) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	deleted, err := uc.UserRepo.DeleteKeepingAdmin(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrLastAdmin
+	}
+	uc.forget(id)
+	return nil
+}
+
+// Account returns the current role of the token's user and whether it may still act; a
+// deleted or disabled user may not. Lookups are cached briefly since every request makes one.
+func (uc *UserUseCase) Account(ctx context.Context, id uuid.UUID) (string, bool, error) {
+	uc.mu.Lock()
+	cached, ok := uc.accounts[id]
+	uc.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.role, cached.active, nil
 	}
 
-	return uc.UserRepo.Create(user)
+	user, err := uc.UserRepo.FindByID(ctx, id)
+	if err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+		return "", false, err
+	}
+	cached = cachedAccount{active: err == nil && !user.Disabled, expires: time.Now().Add(accountCacheTTL)}
+	if err == nil {
+		cached.role = user.Role
+	}
+	uc.mu.Lock()
+	uc.accounts[id] = cached
+	uc.mu.Unlock()
+	return cached.role, cached.active, nil
+}
+
+func (uc *UserUseCase) forget(id uuid.UUID) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	delete(uc.accounts, id)
 }
 
-func (uc *UserUseCase) Login(username, password string) (*entities.User, error) {
-	user, err := uc.UserRepo.FindByUsername(username)
+func (uc *UserUseCase) Login(ctx context.Context, username, password string) (*entities.User, error) {
+	user, err := uc.UserRepo.FindByUsername(ctx, username)
 	if err != nil {
+		if isQueryInterrupted(err) {
+			return nil, err
+		}
 		return nil, errors.New("invalid credentials")
 	}
 
@@ -44,13 +213,19 @@ func (uc *UserUseCase) Login(username, password string) (*entities.User, error)
 	if err != nil {
 		return nil, errors.New("invalid credentials")
 	}
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
 
 	return user, nil
 }
 
-func (uc *UserUseCase) ChangePassword(username, oldPassword, newPassword string) error {
-	user, err := uc.UserRepo.FindByUsername(username)
+func (uc *UserUseCase) ChangePassword(ctx context.Context, userID uuid.UUID, oldPassword, newPassword string) error {
+	user, err := uc.UserRepo.FindByID(ctx, userID)
 	if err != nil {
+		if isQueryInterrupted(err) {
+			return err
+		}
 		return errors.New("user not found")
 	}
 
@@ -58,6 +233,9 @@ func (uc *UserUseCase) ChangePassword(username, oldPassword, newPassword string)
 	if err != nil {
 		return errors.New("invalid old password")
 	}
+	if len(newPassword) < minPasswordLength {
+		return ErrWeakPassword
+	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
@@ -67,5 +245,10 @@ func (uc *UserUseCase) ChangePassword(username, oldPassword, newPassword string)
 	user.Password = string(hashedPassword)
 	user.UpdatedAt = time.Now()
 
-	return uc.UserRepo.Update(user)
+	return uc.UserRepo.Update(ctx, user)
+}
+
+// isQueryInterrupted reports whether err is a timeout/cancellation that must not be masked as a domain error.
+func isQueryInterrupted(err error) bool {
+	return errors.Is(err, interfaces.ErrQueryTimeout) || errors.Is(err, interfaces.ErrQueryCanceled)
 }
@@ -2,7 +2,6 @@
 #include <WiFi.h>
 #include <HTTPClient.h>
 #include <ArduinoJson.h>
-#include "mbedtls/base64.h" // Required for Base64 encoding
 
 /* ===== CONFIGURATION ===== */
 const char* ssid = "1";
@@ -35,8 +34,8 @@ const String API_PASS = "password123";
 
 String jwtToken = "";
 unsigned long lastCaptureTime = 0;
-// Reduced interval for higher FPS (try 0-100 depending on network)
-const int captureInterval = 50; 
+// Milliseconds between frames; the backend replies with the camera's target_fps and this follows it
+unsigned long captureInterval = 200;
 
 /* ===== FUNCTION PROTOTYPES ===== */
 void login();
@@ -159,70 +158,33 @@ void sendFrame() {
     return;
   }
 
-  // 1. Encode Image to Base64
-  // Formula for Base64 length: 4 * ((n + 2) / 3)
-  size_t b64Len = 4 * ((fb->len + 2) / 3);
-  
-  // Allocate memory for Base64 string + null terminator
-  unsigned char* b64Buffer = (unsigned char*)ps_malloc(b64Len + 1);
-  if (!b64Buffer) {
-    // Fallback to heap if PSRAM not available
-    b64Buffer = (unsigned char*)malloc(b64Len + 1);
-  }
-
-  if (b64Buffer) {
-    size_t olen = 0;
-    mbedtls_base64_encode(b64Buffer, b64Len + 1, &olen, fb->buf, fb->len);
-    b64Buffer[olen] = 0; // Null terminate
-
-    // 2. Construct JSON Payload
-    // We need a large buffer for the JSON: 
-    // {"device_id":"...","image_url":"data:image/jpeg;base64,..."}
-    // Overhead is small (~100 chars) + b64Len
-    
-    HTTPClient http;
-    String url = BASE_URL + "/images/stream";
-    http.begin(url);
-    http.addHeader("Content-Type", "application/json");
-    http.addHeader("Authorization", "Bearer " + jwtToken);
-
-    // We will stream the JSON to avoid allocating another huge buffer
-    // But HTTPClient.POST(String) requires the whole string.
-    // Let's try to allocate one big buffer for the JSON.
-    
-    size_t jsonLen = b64Len + 200;
-    char* jsonBuffer = (char*)ps_malloc(jsonLen);
-    if (!jsonBuffer) jsonBuffer = (char*)malloc(jsonLen);
-
-    if (jsonBuffer) {
-      // Build JSON manually to be efficient
-      strcpy(jsonBuffer, "{\"device_id\":\"");
-      strcat(jsonBuffer, DEVICE_ID.c_str());
-      strcat(jsonBuffer, "\",\"image_url\":\"data:image/jpeg;base64,");
-      strcat(jsonBuffer, (char*)b64Buffer);
-      strcat(jsonBuffer, "\"}");
-
-      int httpCode = http.POST(jsonBuffer);
-      
-      if (httpCode == 200) {
-        Serial.printf("Frame sent! Size: %d bytes\n", fb->len);
-      } else if (httpCode == 401) {
-        Serial.println("Token expired");
-        jwtToken = "";
-      } else {
-        Serial.printf("Upload failed, HTTP code: %d\n", httpCode);
-      }
-      
-      free(jsonBuffer);
-    } else {
-      Serial.println("JSON Buffer Malloc failed");
+  // Upload the JPEG as-is; the device is identified by header, so no Base64/JSON copy is needed
+  HTTPClient http;
+  String url = BASE_URL + "/images/upload";
+  http.begin(url);
+  http.addHeader("Content-Type", "image/jpeg");
+  http.addHeader("X-Device-ID", DEVICE_ID);
+  http.addHeader("Authorization", "Bearer " + jwtToken);
+
+  int httpCode = http.POST(fb->buf, fb->len);
+
+  if (httpCode == 200 || httpCode == 202) {
+    // 202 means the frame was dropped for arriving faster than target_fps
+    Serial.printf("Frame %s! Size: %d bytes\n", httpCode == 200 ? "sent" : "dropped", fb->len);
+    StaticJsonDocument<64> filter;
+    filter["target_fps"] = true;
+    DynamicJsonDocument respDoc(256);
+    if (!deserializeJson(respDoc, http.getString(), DeserializationOption::Filter(filter))) {
+      float fps = respDoc["target_fps"] | 0.0;
+      captureInterval = fps > 0 ? (unsigned long)(1000.0 / fps) : 0;
     }
-    
-    free(b64Buffer);
-    http.end();
+  } else if (httpCode == 401) {
+    Serial.println("Token expired");
+    jwtToken = "";
   } else {
-    Serial.println("Base64 Buffer Malloc failed");
+    Serial.printf("Upload failed, HTTP code: %d\n", httpCode);
   }
+  http.end();
 
   esp_camera_fb_return(fb);
 }
@@ -268,7 +268,8 @@ void sendSensorData(float temp, float hum, float vibration, bool fall, bool &loc
 
   int httpResponseCode = http.POST(requestBody);
 
-  if (httpResponseCode == 200 || httpResponseCode == 201) {
+  // The server queues readings and replies 202 Accepted, with the buzzer command
+  if (httpResponseCode == 200 || httpResponseCode == 201 || httpResponseCode == 202) {
     String response = http.getString();
     Serial.println("✅ Data Sent Successfully");
     
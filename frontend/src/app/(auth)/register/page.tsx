@@ -9,7 +9,6 @@ import { UserPlus, Loader2, ShieldCheck } from "lucide-react";
 export default function RegisterPage() {
   const [username, setUsername] = useState("");
   const [password, setPassword] = useState("");
-  const [role, setRole] = useState("Worker");
   const [error, setError] = useState("");
   const [isLoading, setIsLoading] = useState(false);
   const router = useRouter();
@@ -20,7 +19,7 @@ export default function RegisterPage() {
     setIsLoading(true);
 
     try {
-      await api.post("/register", { username, password, role });
+      await api.post("/register", { username, password });
       router.push("/login?registered=true");
     } catch (err: any) {
       console.error("Registration error:", err);
@@ -39,10 +38,10 @@ export default function RegisterPage() {
             <ShieldCheck className="h-7 w-7 text-primary" />
           </div>
           <h2 className="text-center text-3xl font-bold tracking-tight text-foreground">
-            Create an account
+            Set up MineSense
           </h2>
           <p className="mt-2 text-center text-sm text-muted-foreground">
-            Join the safety monitoring network
+            The first account becomes the Admin. Later accounts are created by an Admin.
           </p>
         </div>
 
@@ -64,23 +63,6 @@ export default function RegisterPage() {
               />
             </div>
 
-            <div>
-              <label htmlFor="role" className="block text-sm font-medium text-muted-foreground mb-1">
-                Role
-              </label>
-              <select
-                id="role"
-                name="role"
-                className="block w-full rounded-lg border border-input bg-background py-2.5 px-4 text-foreground shadow-sm focus:ring-2 focus:ring-inset focus:ring-primary sm:text-sm sm:leading-6 [&>option]:bg-background"
-                value={role}
-                onChange={(e) => setRole(e.target.value)}
-              >
-                <option value="Worker">Worker</option>
-                <option value="Supervisor">Supervisor</option>
-                <option value="Admin">Admin</option>
-              </select>
-            </div>
-
             <div>
               <label htmlFor="password" className="block text-sm font-medium text-muted-foreground mb-1">
                 Password
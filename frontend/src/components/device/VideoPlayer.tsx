@@ -2,7 +2,7 @@
 
 import React, { useEffect, useState } from 'react';
 import { VideoOff, Loader2 } from 'lucide-react';
-import { api } from '@/lib/api';
+import { api, mediaUrl } from '@/lib/api';
 import { useWebSocket } from '@/context/WebSocketContext';
 
 interface VideoPlayerProps {
@@ -25,8 +25,8 @@ export const VideoPlayer = ({ deviceId }: VideoPlayerProps) => {
       try {
         const res = await api.get(`/images/latest?device_id=${deviceId}`);
         if (res.data && res.data.image_url) {
-          setImageUrl(res.data.image_url);
-          setLastUpdated(new Date(res.data.created_at));
+          setImageUrl(mediaUrl(res.data.image_url));
+          setLastUpdated(new Date(res.data.timestamp));
         }
       } catch (err) {
         // Silent fail
@@ -2,7 +2,7 @@
 
 import React, { createContext, useContext, useEffect, useState, useRef, ReactNode } from 'react';
 import { WebSocketMessage, DeviceStatus, Alert, SensorPayload } from '../types';
-import { api } from '@/lib/api';
+import { api, mediaUrl } from '@/lib/api';
 import { useAuth } from './AuthContext';
 
 interface WebSocketContextType {
@@ -26,6 +26,7 @@ export const WebSocketProvider = ({ children }: { children: ReactNode }) => {
   
   const ws = useRef<WebSocket | null>(null);
   const reconnectTimeout = useRef<NodeJS.Timeout | null>(null);
+  const lastSeq = useRef<number | null>(null);
 
   const connect = () => {
     // Determine WS URL based on environment or fallback
@@ -34,10 +35,17 @@ export const WebSocketProvider = ({ children }: { children: ReactNode }) => {
     const wsBase = apiUrl.replace(/^http/, 'ws');
     const wsUrl = `${wsBase.replace('/api/v1', '')}/api/v1/ws`;
     
-    ws.current = new WebSocket(wsUrl);
+    // Negotiate the versioned event envelope
+    ws.current = new WebSocket(wsUrl, ['minesense.v2']);
 
     ws.current.onopen = () => {
       console.log('WebSocket Connected');
+      // Browsers cannot set headers on WebSocket requests, so authenticate with the first message.
+      // After a reconnect, last_seq makes the server replay what was missed while offline.
+      const token = localStorage.getItem('token');
+      const auth: Record<string, unknown> = { action: 'auth', token };
+      if (lastSeq.current !== null) auth.last_seq = lastSeq.current;
+      ws.current?.send(JSON.stringify(auth));
       setIsConnected(true);
       if (reconnectTimeout.current) clearTimeout(reconnectTimeout.current);
     };
@@ -57,6 +65,9 @@ export const WebSocketProvider = ({ children }: { children: ReactNode }) => {
     ws.current.onmessage = (event) => {
       try {
         const message: WebSocketMessage = JSON.parse(event.data);
+        if (typeof message.seq === 'number' && (lastSeq.current === null || message.seq > lastSeq.current)) {
+          lastSeq.current = message.seq;
+        }
         setLastMessage(message);
         handleMessage(message);
       } catch (e) {
@@ -66,12 +77,13 @@ export const WebSocketProvider = ({ children }: { children: ReactNode }) => {
   };
 
   const handleMessage = (msg: WebSocketMessage) => {
-    if (msg.type === 'image_update' && msg.payload) {
-      const payload = msg.payload as any; // Assuming payload has device_id and image_url
-      if (payload.device_id && payload.image_url) {
+    if (msg.type === 'image_update' && msg.device_id && msg.payload) {
+      const deviceId = msg.device_id;
+      const imageUrl = msg.payload.image_url;
+      if (imageUrl) {
         setLatestImages(prev => {
           const newMap = new Map(prev);
-          newMap.set(payload.device_id, payload.image_url);
+          newMap.set(deviceId, mediaUrl(imageUrl));
           return newMap;
         });
       }
@@ -87,7 +99,7 @@ export const WebSocketProvider = ({ children }: { children: ReactNode }) => {
         };
 
         // Update readings
-        const payload = msg.payload as SensorPayload;
+        const payload = (msg.payload?.readings || {}) as SensorPayload;
         current.current_readings = { ...current.current_readings, ...payload };
         current.last_seen = msg.timestamp || new Date().toISOString();
         current.is_online = true;
@@ -2,6 +2,9 @@ import axios from 'axios';
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || 'https://mining-hazard-detection-and-safety.onrender.com/api/v1';
 
+// Resolves media URLs returned by the API (e.g. signed frame URLs), which are relative to the API origin.
+export const mediaUrl = (url: string) => (url.startsWith('/') ? new URL(API_URL).origin + url : url);
+
 export const api = axios.create({
   baseURL: API_URL,
   headers: {
@@ -41,11 +41,13 @@ export interface Alert {
   acknowledged: boolean;
 }
 
+// Real-time event envelope (protocol version 2); schema at /api/v1/events/schema
 export interface WebSocketMessage {
-  type: 'sensor_update' | 'alert_new' | 'device_status' | 'image_update';
+  type: 'sensor_update' | 'alert' | 'image_update' | 'device_command' | 'authenticated' | 'subscribed' | 'replay_complete' | 'error';
+  version: number;
+  id: string;
+  seq?: number; // Hub sequence ID, sent back as last_seq to resume after a reconnect
+  timestamp: string;
   device_id?: string;
-  payload?: any;
-  timestamp?: string;
-  // For sensor_update specifically
-  sensor_type?: string;
+  payload: any; // sensor_update: { sensor_type, readings }, image_update: { image_id, image_url, size_bytes, motion_score? }, alert: Alert
 }
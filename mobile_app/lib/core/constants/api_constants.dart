@@ -3,4 +3,8 @@ class ApiConstants {
   static const String loginEndpoint = '/login';
   static const String registerEndpoint = '/register';
   // Add other endpoints as needed
+
+  // Media URLs from the API (e.g. signed frame URLs) are relative to the API origin
+  static String mediaUrl(String url) =>
+      url.startsWith('/') ? Uri.parse(baseUrl).resolve(url).toString() : url;
 }
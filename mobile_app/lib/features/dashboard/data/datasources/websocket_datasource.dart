@@ -8,6 +8,8 @@ import '../models/device_model.dart';
 class WebSocketDataSource {
   final SharedPreferences sharedPreferences;
   WebSocketChannel? _channel;
+  // Highest hub sequence ID seen; sent as last_seq on reconnect to replay missed messages
+  int? _lastSeq;
   final _deviceStreamController = StreamController<Map<String, dynamic>>.broadcast();
 
   WebSocketDataSource(this.sharedPreferences);
@@ -21,20 +23,24 @@ class WebSocketDataSource {
     // Construct WS URL
     // Base URL: https://mining-hazard-detection-and-safety.onrender.com/api/v1
     // WS URL: wss://mining-hazard-detection-and-safety.onrender.com/api/v1/ws
-    final wsUrl = ApiConstants.baseUrl.replaceFirst('https', 'wss') + '/ws';
-    
-    // Note: If the backend requires token in headers or query params for WS, add it here.
-    // Usually standard WS doesn't support headers in browser, but in Flutter it does.
-    // However, the backend might expect it in a specific way.
-    // Assuming standard connection for now.
+    // The backend authenticates the WebSocket with the JWT in the token query parameter
+    var wsUrl = ApiConstants.baseUrl.replaceFirst('https', 'wss') + '/ws?token=' + Uri.encodeQueryComponent(token);
+    if (_lastSeq != null) {
+      wsUrl += '&last_seq=$_lastSeq';
+    }
     
     try {
-      _channel = WebSocketChannel.connect(Uri.parse(wsUrl));
+      // Negotiate the versioned event envelope (schema at /api/v1/events/schema)
+      _channel = WebSocketChannel.connect(Uri.parse(wsUrl), protocols: ['minesense.v2']);
       
       _channel!.stream.listen(
         (message) {
           try {
             final data = json.decode(message);
+            final seq = data['seq'];
+            if (seq is int && (_lastSeq == null || seq > _lastSeq!)) {
+              _lastSeq = seq;
+            }
             _deviceStreamController.add(data);
           } catch (e) {
             print('Error parsing WS message: $e');
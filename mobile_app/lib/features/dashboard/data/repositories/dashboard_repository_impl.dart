@@ -1,4 +1,5 @@
 import 'dart:async';
+import '../../../../core/constants/api_constants.dart';
 import '../../domain/entities/device.dart';
 import '../../domain/repositories/dashboard_repository.dart';
 import '../datasources/websocket_datasource.dart';
@@ -51,12 +52,14 @@ class DashboardRepositoryImpl implements DashboardRepository {
   }
 
   void _handleMessage(Map<String, dynamic> message) {
+    // Protocol v2 envelope: type, version, id, timestamp, device_id, payload
     final type = message['type'];
-    
+    final deviceId = message['device_id'];
+    final payload = message['payload'];
+
     if (type == 'sensor_update') {
-      final deviceId = message['device_id'];
       if (deviceId != null) {
-        final payload = message['payload'];
+        final readings = payload is Map ? payload['readings'] : null;
         final timestamp = message['timestamp'];
 
         DeviceModel current = _devicesMap[deviceId] ??
@@ -68,7 +71,7 @@ class DashboardRepositoryImpl implements DashboardRepository {
             );
 
         // Update logic similar to frontend
-        final sensorPayload = SensorPayloadModel.fromJson(payload ?? {});
+        final sensorPayload = SensorPayloadModel.fromJson(readings ?? {});
         
         String status = 'Safe';
         if (sensorPayload.fall == true) status = 'Critical';
@@ -87,9 +90,7 @@ class DashboardRepositoryImpl implements DashboardRepository {
         _devicesController.add(_devicesMap.values.toList());
       }
     } else if (type == 'image_update') {
-      final payload = message['payload'];
-      if (payload != null) {
-        final deviceId = payload['device_id'];
+      if (payload is Map) {
         final imageUrl = payload['image_url'];
         
         if (deviceId != null && imageUrl != null) {
@@ -102,7 +103,7 @@ class DashboardRepositoryImpl implements DashboardRepository {
               );
               
           final updated = current.copyWith(
-            latestImageUrl: imageUrl,
+            latestImageUrl: ApiConstants.mediaUrl(imageUrl),
             lastSeen: DateTime.now(),
           );
           
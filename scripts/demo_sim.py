@@ -32,6 +32,7 @@ print(f"Targeting API Base: {BASE_URL}")
 SENSOR_DATA_URL = f"{BASE_URL}/api/v1/sensor-data"
 LOGIN_URL = f"{BASE_URL}/api/v1/login"
 REGISTER_URL = f"{BASE_URL}/api/v1/register"
+USERS_URL = f"{BASE_URL}/api/v1/users"
 DEVICES_URL = f"{BASE_URL}/api/v1/devices"
 IMAGES_URL = f"{BASE_URL}/api/v1/images"
 STREAM_URL = f"{BASE_URL}/api/v1/images/stream"
@@ -43,8 +44,12 @@ ADMIN_PASSWORD = "password123"
 SUPERVISOR_USERNAME = "demo_supervisor"
 SUPERVISOR_PASSWORD = "password123"
 
-def get_auth_token(username, password, role="User"):
-    """Authenticates the user and returns the JWT token and User ID."""
+def get_auth_token(username, password, role="Worker", admin_token=None):
+    """Authenticates the user and returns the JWT token and User ID.
+
+    Missing users are created by the Admin behind admin_token; without one, the user is
+    registered as the first Admin (registration closes once any user exists).
+    """
     print(f"Authenticating as {username}...")
     
     # Try to login first
@@ -58,9 +63,14 @@ def get_auth_token(username, password, role="User"):
             return data.get("token"), data.get("user", {}).get("id")
         elif response.status_code == 400 or response.status_code == 404 or response.status_code == 401:
              # If login fails, try to register
-            print("Login failed, attempting registration...")
-            register_payload = {"username": username, "password": password, "role": role}
-            reg_response = requests.post(REGISTER_URL, json=register_payload)
+            if admin_token:
+                print("Login failed, creating user as Admin...")
+                register_payload = {"username": username, "password": password, "role": role}
+                reg_response = requests.post(USERS_URL, json=register_payload, headers={"Authorization": f"Bearer {admin_token}"})
+            else:
+                print("Login failed, attempting registration...")
+                register_payload = {"username": username, "password": password}
+                reg_response = requests.post(REGISTER_URL, json=register_payload)
             
             if reg_response.status_code == 200 or reg_response.status_code == 201:
                 print("Registration successful. Logging in...")
@@ -100,7 +110,8 @@ def create_device(token, supervisor_id=None):
     headers = {"Authorization": f"Bearer {token}"}
     payload = {
         "device_name": f"Simulated Device {uuid.uuid4().hex[:8]}",
-        "location": "Simulation Script"
+        "location": "Simulation Script",
+        "has_camera": True  # The simulated device also streams webcam frames
     }
     if supervisor_id:
         payload["supervisor_id"] = supervisor_id
@@ -252,20 +263,20 @@ def simulate_fall():
     }
 
 def run_demo():
-    # 1. Get Supervisor ID (Create if needed)
-    print("--- Setup: Supervisor ---")
-    _, supervisor_id = get_auth_token(SUPERVISOR_USERNAME, SUPERVISOR_PASSWORD, "Supervisor")
-    if not supervisor_id:
-        print("Failed to get Supervisor ID.")
-        return
-
-    # 2. Get Admin Token (to create device)
-    print("\n--- Setup: Admin ---")
+    # 1. Get Admin Token (to create users and devices)
+    print("--- Setup: Admin ---")
     admin_token, _ = get_auth_token(ADMIN_USERNAME, ADMIN_PASSWORD, "Admin")
     if not admin_token:
         print("Failed to get Admin Token.")
         return
 
+    # 2. Get Supervisor ID (Create if needed)
+    print("\n--- Setup: Supervisor ---")
+    _, supervisor_id = get_auth_token(SUPERVISOR_USERNAME, SUPERVISOR_PASSWORD, "Supervisor", admin_token)
+    if not supervisor_id:
+        print("Failed to get Supervisor ID.")
+        return
+
     # 3. Create Device assigned to Supervisor
     device_id = create_device(admin_token, supervisor_id)
     if not device_id: